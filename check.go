@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/topi314/prometheus-collectors/exporters"
+)
+
+// checkReport is the result of checkConfig, it is printed as JSON with -json.
+type checkReport struct {
+	Config    string              `json:"config"`
+	Valid     bool                `json:"valid"`
+	Errors    []string            `json:"errors"`
+	Warnings  []string            `json:"warnings"`
+	Exporters []checkExporterInfo `json:"exporters"`
+}
+
+type checkExporterInfo struct {
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Error   string `json:"error,omitempty"`
+}
+
+// runCheckConfig implements the check-config subcommand and returns the exit code.
+func runCheckConfig(args []string) int {
+	fs := flag.NewFlagSet("check-config", flag.ExitOnError)
+	cfgPath := fs.String("config", "config.toml", "Path to config file")
+	jsonReport := fs.Bool("json", false, "Print the report as JSON")
+	_ = fs.Parse(args)
+
+	report := checkConfig(*cfgPath)
+
+	var err error
+	if *jsonReport {
+		err = printCheckReportJSON(os.Stdout, report)
+	} else {
+		err = printCheckReport(os.Stdout, report)
+	}
+	if err != nil {
+		slog.Error("Failed to print report", slog.Any("err", err))
+		return 2
+	}
+
+	if !report.Valid {
+		return 1
+	}
+	return 0
+}
+
+// checkConfig loads and validates the config and creates every exporter and probe module without starting them.
+func checkConfig(path string) checkReport {
+	report := checkReport{
+		Config:    path,
+		Errors:    []string{},
+		Warnings:  []string{},
+		Exporters: []checkExporterInfo{},
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+	if err = cfg.Validate(); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	// all exporters share one registry like they share the default registry when running, so conflicting metrics are reported
+	registry := exporters.NewRegistry(prometheus.NewRegistry())
+
+	for _, config := range cfg.Configs {
+		config = cfg.Global.withDefaults(config)
+		for _, warning := range config.Warnings() {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("configs %q: %s", config.Name, warning))
+		}
+		report.Exporters = append(report.Exporters, checkExporter("configs", config, registry))
+	}
+	for _, module := range cfg.Probe.modulesWithTarget("probe-target") {
+		if module.Timeout == 0 {
+			module.Timeout = cfg.Global.ScrapeTimeout
+		}
+		// probe modules use their own registry per probe
+		report.Exporters = append(report.Exporters, checkExporter("probe.modules", module, exporters.NewRegistry(prometheus.NewRegistry())))
+	}
+
+	report.Valid = len(report.Errors) == 0
+	for _, exporter := range report.Exporters {
+		if exporter.Error != "" {
+			report.Valid = false
+		}
+	}
+	return report
+}
+
+func checkExporter(section string, cfg exporters.Config, registry *exporters.Registry) checkExporterInfo {
+	info := checkExporterInfo{
+		Section: section,
+		Name:    cfg.Name,
+		Type:    cfg.Type,
+	}
+
+	// the metrics are kept until all exporters are checked to detect conflicts between them
+	exporter, err := exporters.New(cfg, slog.Default(), registry.Factory())
+	if err != nil {
+		if errors.Is(err, exporters.ErrExporterNotFound) {
+			err = fmt.Errorf("%w: %s", err, cfg.Type)
+		}
+		info.Error = err.Error()
+		return info
+	}
+	if err = exporter.Close(); err != nil {
+		info.Error = fmt.Sprintf("close exporter: %s", err)
+	}
+	return info
+}
+
+func printCheckReportJSON(w io.Writer, report checkReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func printCheckReport(w io.Writer, report checkReport) error {
+	var errs []error
+	printf := func(format string, args ...any) {
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, err := range report.Errors {
+		printf("error: %s\n", err)
+	}
+	for _, warning := range report.Warnings {
+		printf("warning: %s\n", warning)
+	}
+	for _, exporter := range report.Exporters {
+		if exporter.Error != "" {
+			printf("FAIL %s %q (%s): %s\n", exporter.Section, exporter.Name, exporter.Type, exporter.Error)
+			continue
+		}
+		printf("ok   %s %q (%s)\n", exporter.Section, exporter.Name, exporter.Type)
+	}
+	if report.Valid {
+		printf("%s is valid\n", report.Config)
+	} else {
+		printf("%s is invalid\n", report.Config)
+	}
+	return errors.Join(errs...)
+}
@@ -4,13 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net"
+	"net/url"
 	"os"
+	"path"
+	"strings"
 	"time"
 
-	"github.com/pelletier/go-toml/v2"
-
 	"github.com/topi314/prometheus-collectors/exporters"
+	"github.com/topi314/prometheus-collectors/internal/env"
 	"github.com/topi314/prometheus-collectors/internal/xtime"
+	"github.com/topi314/prometheus-collectors/internal/xtoml"
 )
 
 func defaultConfig() Config {
@@ -18,6 +23,8 @@ func defaultConfig() Config {
 		Global: GlobalConfig{
 			ScrapeInterval: xtime.Duration(1 * time.Minute),
 			ScrapeTimeout:  xtime.Duration(10 * time.Second),
+			StaleMode:      exporters.StaleModeDelete,
+			Backoff:        exporters.DefaultBackoffConfig,
 		},
 		Log: LogConfig{
 			Level:     slog.LevelInfo,
@@ -25,32 +32,76 @@ func defaultConfig() Config {
 			AddSource: false,
 		},
 		Server: ServerConfig{
-			ListenAddr: ":2112",
-			Endpoint:   "/metrics",
+			ListenAddr:      ":2112",
+			Endpoint:        "/metrics",
+			ShutdownTimeout: xtime.Duration(5 * time.Second),
 		},
 	}
 }
 
 func loadConfig(path string) (Config, error) {
-	f, err := os.Open(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return Config{}, fmt.Errorf("failed to open config file: %w", err)
+		return Config{}, fmt.Errorf("failed to read config file: %w", err)
 	}
-	defer func() {
-		_ = f.Close()
-	}()
 
 	cfg := defaultConfig()
-	if err = toml.NewDecoder(f).Decode(&cfg); err != nil {
+	if err = xtoml.Decode(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to decode config file: %w", err)
+	}
+
+	lines, err := xtoml.KeyLines(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err = checkUnknownOptions(cfg, lines); err != nil {
 		return Config{}, fmt.Errorf("failed to decode config file: %w", err)
 	}
+
+	if cfg.Global.DeprecatedScrapeTimeout != nil {
+		slog.Warn("global config scape_timeout is deprecated, use scrape_timeout instead", slog.Int("line", lines["global.scape_timeout"]))
+		if _, ok := lines["global.scrape_timeout"]; !ok {
+			cfg.Global.ScrapeTimeout = *cfg.Global.DeprecatedScrapeTimeout
+		}
+	}
+
+	if err = env.Expand(&cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to expand environment variables: %w", err)
+	}
 	return cfg, nil
 }
 
+// checkUnknownOptions decodes the options of all exporters and probe modules and reports unknown keys with their line in the config file.
+// Other option errors are left to Validate.
+func checkUnknownOptions(cfg Config, lines map[string]int) error {
+	var keys []xtoml.UnknownKey
+	check := func(prefix string, configs exporters.Configs) {
+		for i, config := range configs {
+			var unknownErr *xtoml.UnknownKeysError
+			if !errors.As(exporters.ValidateOptions(config.Type, config.Options), &unknownErr) {
+				continue
+			}
+			for _, key := range unknownErr.Keys {
+				key.Key = append([]string{fmt.Sprintf("%s[%d]", prefix, i), "options"}, key.Key...)
+				key.Line = xtoml.Line(lines, key.Key)
+				keys = append(keys, key)
+			}
+		}
+	}
+	check("configs", cfg.Configs)
+	check("probe.modules", cfg.Probe.Modules)
+
+	if len(keys) == 0 {
+		return nil
+	}
+	return &xtoml.UnknownKeysError{Keys: keys}
+}
+
 type Config struct {
 	Global  GlobalConfig      `toml:"global"`
 	Log     LogConfig         `toml:"log"`
 	Server  ServerConfig      `toml:"server"`
+	Probe   ProbeConfig       `toml:"probe"`
 	Configs exporters.Configs `toml:"configs"`
 }
 
@@ -65,6 +116,9 @@ func (c Config) Validate() error {
 	if err := c.Server.Validate(); err != nil {
 		errs = append(errs, fmt.Errorf("server: %w", err))
 	}
+	if err := c.Probe.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("probe: %w", err))
+	}
 	if err := c.Configs.Validate(); err != nil {
 		errs = append(errs, fmt.Errorf("configs: %w", err))
 	}
@@ -72,17 +126,27 @@ func (c Config) Validate() error {
 }
 
 func (c Config) String() string {
-	return fmt.Sprintf("\n global: %v\n log: %v\n server: %v\n configs: %v",
+	return fmt.Sprintf("\n global: %v\n log: %v\n server: %v\n probe: %v\n configs: %v",
 		c.Global,
 		c.Log,
 		c.Server,
+		c.Probe,
 		c.Configs,
 	)
 }
 
 type GlobalConfig struct {
 	ScrapeInterval xtime.Duration `toml:"scrape_interval"`
-	ScrapeTimeout  xtime.Duration `toml:"scape_timeout"`
+	ScrapeTimeout  xtime.Duration `toml:"scrape_timeout"`
+	// DeprecatedScrapeTimeout is the old misspelled scrape_timeout key, it is only used if scrape_timeout is not set.
+	DeprecatedScrapeTimeout *xtime.Duration `toml:"scape_timeout"`
+	// StaleAfter is the default duration without a successful scrape after which series are marked stale, 0 disables it.
+	StaleAfter xtime.Duration      `toml:"stale_after"`
+	StaleMode  exporters.StaleMode `toml:"stale_mode"`
+	// Backoff is the default backoff config of the exporters.
+	Backoff exporters.BackoffConfig `toml:"backoff"`
+	// MaxConcurrentScrapes limits the number of exporters collecting at the same time, 0 disables the limit.
+	MaxConcurrentScrapes int `toml:"max_concurrent_scrapes"`
 }
 
 func (g GlobalConfig) Validate() error {
@@ -93,16 +157,53 @@ func (g GlobalConfig) Validate() error {
 	if g.ScrapeTimeout <= 0 {
 		errs = append(errs, fmt.Errorf("global config scrape_timeout must be greater than 0"))
 	}
+	if g.StaleAfter < 0 {
+		errs = append(errs, fmt.Errorf("global config stale_after must not be negative"))
+	}
+	if err := g.StaleMode.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("global config stale_mode: %w", err))
+	}
+	if err := g.Backoff.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("global config backoff: %w", err))
+	}
+	if g.MaxConcurrentScrapes < 0 {
+		errs = append(errs, fmt.Errorf("global config max_concurrent_scrapes must not be negative"))
+	}
 	return errors.Join(errs...)
 }
 
 func (g GlobalConfig) String() string {
-	return fmt.Sprintf("\n  scrape_interval: %s\n  scrape_timeout: %s",
+	return fmt.Sprintf("\n  scrape_interval: %s\n  scrape_timeout: %s\n  stale_after: %s\n  stale_mode: %s\n  backoff: %s\n  max_concurrent_scrapes: %d",
 		time.Duration(g.ScrapeInterval).String(),
 		time.Duration(g.ScrapeTimeout).String(),
+		time.Duration(g.StaleAfter).String(),
+		g.StaleMode,
+		g.Backoff,
+		g.MaxConcurrentScrapes,
 	)
 }
 
+// withDefaults fills the unset settings of the exporter config with the global defaults.
+func (g GlobalConfig) withDefaults(config exporters.Config) exporters.Config {
+	if config.Interval == 0 {
+		config.Interval = g.ScrapeInterval
+	}
+	if config.Timeout == 0 {
+		config.Timeout = g.ScrapeTimeout
+	}
+	if config.StaleAfter == 0 {
+		config.StaleAfter = g.StaleAfter
+	}
+	if config.StaleMode == "" {
+		config.StaleMode = g.StaleMode
+	}
+	if config.Mode == "" {
+		config.Mode = exporters.ModeTimer
+	}
+	config.Backoff = config.Backoff.WithDefaults(g.Backoff.WithDefaults(exporters.DefaultBackoffConfig))
+	return config
+}
+
 type LogConfig struct {
 	Level     slog.Level `toml:"level"`
 	Format    string     `toml:"format"`
@@ -127,6 +228,8 @@ func (l LogConfig) String() string {
 type ServerConfig struct {
 	ListenAddr string `toml:"listen_addr"`
 	Endpoint   string `toml:"endpoint"`
+	// ShutdownTimeout bounds waiting for the collections in flight and, separately, shutting the server down on SIGTERM or SIGINT.
+	ShutdownTimeout xtime.Duration `toml:"shutdown_timeout"`
 }
 
 func (s ServerConfig) Validate() error {
@@ -137,12 +240,121 @@ func (s ServerConfig) Validate() error {
 	if s.Endpoint == "" {
 		errs = append(errs, fmt.Errorf("server config endpoint is required"))
 	}
+	if s.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("server config shutdown_timeout must be greater than 0"))
+	}
 	return errors.Join(errs...)
 }
 
 func (s ServerConfig) String() string {
-	return fmt.Sprintf("\n  listen_addr: %s\n  endpoint: %s",
+	return fmt.Sprintf("\n  listen_addr: %s\n  endpoint: %s\n  shutdown_timeout: %s",
 		s.ListenAddr,
 		s.Endpoint,
+		time.Duration(s.ShutdownTimeout).String(),
 	)
 }
+
+type ProbeConfig struct {
+	// AllowedTargets are glob patterns (see path.Match) of the targets which may be probed, no target is allowed if empty.
+	AllowedTargets []string `toml:"allowed_targets"`
+	// Modules are exporter config templates without an address.
+	Modules exporters.Configs `toml:"modules"`
+}
+
+func (p ProbeConfig) Validate() error {
+	var errs []error
+	for _, pattern := range p.AllowedTargets {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("probe config allowed_targets pattern %q: %w", pattern, err))
+		}
+	}
+	names := make(map[string]struct{}, len(p.Modules))
+	for _, module := range p.Modules {
+		if _, ok := names[module.Name]; ok {
+			errs = append(errs, fmt.Errorf("probe config module %q is defined multiple times", module.Name))
+		}
+		names[module.Name] = struct{}{}
+		if _, ok := module.Options["address"]; ok {
+			errs = append(errs, fmt.Errorf("probe config module %q must not define an address", module.Name))
+		}
+		// every probe collects into its own registry, so the metric schemas only have to match within a module
+		if err := (exporters.Configs{withProbeTarget(module, "probe-target")}).Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// modulesWithTarget returns the modules with the target set as address or url host like a probe would.
+func (p ProbeConfig) modulesWithTarget(target string) exporters.Configs {
+	modules := make(exporters.Configs, len(p.Modules))
+	for i, module := range p.Modules {
+		modules[i] = withProbeTarget(module, target)
+	}
+	return modules
+}
+
+func (p ProbeConfig) Module(name string) (exporters.Config, bool) {
+	for _, module := range p.Modules {
+		if module.Name == name {
+			return module, true
+		}
+	}
+	return exporters.Config{}, false
+}
+
+// TargetAllowed reports whether the target is a valid probe target matching one of the allowed patterns.
+func (p ProbeConfig) TargetAllowed(target string) bool {
+	if validateProbeTarget(target) != nil {
+		return false
+	}
+	for _, pattern := range p.AllowedTargets {
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validateProbeTarget checks that the target is a plain host[:port].
+// Otherwise, e.g. 192.168.1.1@evil.com:80 would match the pattern 192.168.1.*:80 but be requested from evil.com.
+func validateProbeTarget(target string) error {
+	if strings.ContainsAny(target, `@/?#\`) {
+		return fmt.Errorf("target %q must be host[:port]", target)
+	}
+	if _, _, err := net.SplitHostPort(target); err != nil && strings.Contains(target, ":") {
+		return fmt.Errorf("target %q must be host[:port]: %w", target, err)
+	}
+	u, err := url.Parse((&url.URL{Scheme: "http", Host: target}).String())
+	if err != nil {
+		return fmt.Errorf("target %q must be host[:port]: %w", target, err)
+	}
+	if u.Host != target || u.Hostname() == "" {
+		return fmt.Errorf("target %q must be host[:port]", target)
+	}
+	return nil
+}
+
+func (p ProbeConfig) String() string {
+	return fmt.Sprintf("\n  allowed_targets: %v\n  modules: %v",
+		p.AllowedTargets,
+		p.Modules,
+	)
+}
+
+// withProbeTarget replaces the host of the module url with the target or sets the target as address.
+func withProbeTarget(module exporters.Config, target string) exporters.Config {
+	module.Options = maps.Clone(module.Options)
+	if module.Options == nil {
+		module.Options = map[string]any{}
+	}
+	if rawURL, ok := module.Options["url"].(string); ok {
+		if u, err := url.Parse(rawURL); err == nil {
+			u.Host = target
+			module.Options["url"] = u.String()
+		}
+		return module
+	}
+	module.Options["address"] = target
+	return module
+}
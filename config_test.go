@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/topi314/prometheus-collectors/exporters"
+)
+
+func TestProbeConfigValidate(t *testing.T) {
+	module := func(name string, help string) exporters.Config {
+		return exporters.Config{
+			Name: name,
+			Type: exporters.HTTPJSONType,
+			Options: map[string]any{
+				"url": "http://localhost/data",
+				"metrics": []any{
+					map[string]any{"name": "test_value", "help": help, "path": "$.value"},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modules exporters.Configs
+		err     string
+	}{
+		{
+			name:    "valid",
+			modules: exporters.Configs{module("a", "Test")},
+		},
+		{
+			name:    "other help in other module",
+			modules: exporters.Configs{module("a", "Test"), module("b", "Other")},
+		},
+		{
+			name:    "duplicate module",
+			modules: exporters.Configs{module("a", "Test"), module("a", "Test")},
+			err:     `probe config module "a" is defined multiple times`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ProbeConfig{Modules: tt.modules}.Validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestProbeConfigTargetAllowed(t *testing.T) {
+	cfg := ProbeConfig{AllowedTargets: []string{"192.168.1.*:80", "sensor-*.lan", `\[fd00::*\]:80`}}
+
+	tests := []struct {
+		target  string
+		allowed bool
+	}{
+		{target: "192.168.1.1:80", allowed: true},
+		{target: "sensor-1.lan", allowed: true},
+		{target: "[fd00::1]:80", allowed: true},
+		{target: "192.168.2.1:80"},
+		{target: "192.168.1.1@evil.com:80"},
+		{target: "192.168.1.1:80/evil.com"},
+		{target: "192.168.1.1:80?evil.com"},
+		{target: "192.168.1.1:80#evil.com"},
+		{target: `192.168.1.1:80\evil.com`},
+		{target: "192.168.1.1:80:80"},
+		{target: "192.168.1.1 :80"},
+		{target: ":80"},
+		{target: "fd00::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := cfg.TargetAllowed(tt.target); got != tt.allowed {
+				t.Errorf("TargetAllowed() = %t, want %t", got, tt.allowed)
+			}
+		})
+	}
+}
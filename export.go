@@ -3,60 +3,412 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
-	"sync"
+	"maps"
+	"net"
+	"reflect"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
 	"github.com/topi314/prometheus-collectors/exporters"
 )
 
-func startExporters(ctx context.Context, cfg Config) {
-	slog.DebugContext(ctx, "starting exporters")
+var (
+	scrapeSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_exporter_scrape_success",
+		Help: "Whether the last scrape of the exporter was successful.",
+	}, []string{"name", "type"})
+	scrapeDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_exporter_scrape_duration_seconds",
+		Help: "Duration of the last scrape of the exporter in seconds.",
+	}, []string{"name", "type"})
+	lastSuccessTimestamp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_exporter_last_success_timestamp_seconds",
+		Help: "Timestamp of the last successful scrape of the exporter.",
+	}, []string{"name", "type"})
+	scrapeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_exporter_scrape_errors_total",
+		Help: "Total number of failed scrapes of the exporter by reason.",
+	}, []string{"name", "type", "reason"})
+	metricErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_exporter_metric_errors_total",
+		Help: "Total number of failures of single metrics in otherwise successful scrapes of the exporter.",
+	}, []string{"name", "type", "metric"})
+	scrapeSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_exporter_scrape_skipped_total",
+		Help: "Total number of scheduled scrapes of the exporter which were skipped because the previous scrape was still running.",
+	}, []string{"name", "type"})
+	circuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_exporter_circuit_breaker_state",
+		Help: "State of the circuit breaker of the exporter, 1 for the current state.",
+	}, []string{"name", "type", "state"})
+)
+
+// failureSummaryInterval is the interval in which a persistently failing exporter logs a summary of its failures.
+const failureSummaryInterval = 10 * time.Minute
+
+// abortGracePeriod is the time exporters get to close after their collections were aborted on shutdown.
+const abortGracePeriod = time.Second
+
+type circuitState string
+
+const (
+	// circuitClosed collects on schedule.
+	circuitClosed circuitState = "closed"
+	// circuitOpen delays the next collection by the backoff.
+	circuitOpen circuitState = "open"
+	// circuitHalfOpen is the trial collection after the backoff, it closes the circuit on success and opens it again on failure.
+	circuitHalfOpen circuitState = "half_open"
+)
+
+var circuitStates = []circuitState{circuitClosed, circuitOpen, circuitHalfOpen}
+
+const (
+	errorReasonTimeout    = "timeout"
+	errorReasonDNS        = "dns"
+	errorReasonConnection = "connection"
+	errorReasonStatusCode = "status_code"
+	errorReasonDecode     = "decode"
+	errorReasonExtract    = "extract"
+	errorReasonOther      = "other"
+)
+
+func errorReason(err error) string {
+	var (
+		dnsErr *net.DNSError
+		netErr net.Error
+		opErr  *net.OpError
+	)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return errorReasonTimeout
+	case errors.As(err, &dnsErr):
+		return errorReasonDNS
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return errorReasonTimeout
+	case errors.As(err, &opErr):
+		return errorReasonConnection
+	case errors.Is(err, exporters.ErrUnexpectedStatusCode):
+		return errorReasonStatusCode
+	case errors.Is(err, exporters.ErrDecode):
+		return errorReasonDecode
+	case errors.Is(err, exporters.ErrExtract):
+		return errorReasonExtract
+	default:
+		return errorReasonOther
+	}
+}
+
+func newExporterManager(ctx context.Context, registry *exporters.Registry, status *statusStore, onDemand *onDemandCollectors) *exporterManager {
+	return &exporterManager{
+		ctx:      ctx,
+		registry: registry,
+		status:   status,
+		onDemand: onDemand,
+		limiter:  &scrapeLimiter{},
+		running:  map[string]*runningExporter{},
+	}
+}
+
+// exporterManager keeps track of the running exporters and starts/stops them when the config changes.
+// It is not safe for concurrent use.
+type exporterManager struct {
+	ctx      context.Context
+	registry *exporters.Registry
+	status   *statusStore
+	onDemand *onDemandCollectors
+	// limiter is shared by all exporters to limit their concurrent collections.
+	limiter *scrapeLimiter
+	running map[string]*runningExporter
+}
+
+type runningExporter struct {
+	cfg exporters.Config
+	// cancel stops scheduling new collections, abort cancels the collections in flight.
+	cancel context.CancelFunc
+	abort  context.CancelFunc
+	done   chan struct{}
+}
 
-	var wg sync.WaitGroup
-	for i := range cfg.Configs {
-		wg.Add(1)
+// Apply diffs the given config against the running exporters by name and only stops/starts the exporters which changed.
+// It returns the errors of the exporters which couldn't be started with their new config.
+func (m *exporterManager) Apply(cfg Config) error {
+	slog.DebugContext(m.ctx, "applying exporter configs")
+	m.limiter.SetLimit(cfg.Global.MaxConcurrentScrapes)
 
-		config := cfg.Configs[i]
-		if config.Interval == 0 {
-			config.Interval = cfg.Global.ScrapeInterval
+	configs := make(map[string]exporters.Config, len(cfg.Configs))
+	for _, config := range cfg.Configs {
+		configs[config.Name] = cfg.Global.withDefaults(config)
+	}
+
+	for name := range m.running {
+		if _, ok := configs[name]; ok {
+			continue
 		}
-		if config.Timeout == 0 {
-			config.Timeout = cfg.Global.ScrapeTimeout
+		slog.InfoContext(m.ctx, "stopping exporter", slog.String("name", name))
+		m.stop(name)
+	}
+
+	// exporters which failed to be created are not running, but have a status
+	for _, status := range m.status.list() {
+		if _, ok := configs[status.Name]; !ok {
+			m.status.remove(status.Name)
 		}
-		go func() {
-			defer wg.Done()
-			logger := slog.With(
-				slog.String("name", config.Name),
-				slog.String("type", config.Type),
-				slog.Duration("interval", time.Duration(config.Interval)),
-				slog.Duration("timeout", time.Duration(config.Timeout)),
-			)
-			collect(ctx, logger, config)
-		}()
 	}
 
-	wg.Wait()
+	var (
+		errs        []error
+		conflicting []exporters.Config
+	)
+	for _, name := range slices.Sorted(maps.Keys(configs)) {
+		config := configs[name]
+		running, ok := m.running[name]
+		if ok && reflect.DeepEqual(config, running.cfg) {
+			continue
+		}
+		if ok {
+			slog.InfoContext(m.ctx, "restarting exporter with changed config", slog.String("name", name))
+		} else {
+			slog.InfoContext(m.ctx, "starting exporter", slog.String("name", name))
+		}
+		for _, warning := range config.Warnings() {
+			slog.WarnContext(m.ctx, "exporter config warning", slog.String("name", name), slog.String("warning", warning))
+		}
+		err := m.replace(config)
+		if ok && errors.Is(err, exporters.ErrMetricConflict) {
+			// the changed metrics conflict with the ones of running exporters, which may change the same way
+			slog.DebugContext(m.ctx, "deferring restart of exporter with conflicting metrics", slog.String("name", name), slog.Any("err", err))
+			conflicting = append(conflicting, config)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("exporter %q: %w", name, err))
+		}
+	}
+	if err := m.replaceConflicting(conflicting); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
 }
 
-func collect(ctx context.Context, logger *slog.Logger, cfg exporters.Config) {
-	slog.DebugContext(ctx, "starting exporter", slog.String("name", cfg.Name))
-	exporter, err := exporters.New(cfg, logger)
+// replace creates the exporter of the config and only then stops the running exporter with the same name and starts the new one.
+// If the exporter can't be created, the running exporter is kept.
+// A running exporter is also kept if the error is exporters.ErrMetricConflict without logging it, see replaceConflicting.
+func (m *exporterManager) replace(cfg exporters.Config) error {
+	logger := exporterLogger(cfg)
+	exporter, factory, err := m.create(cfg, logger)
 	if err != nil {
-		if errors.Is(err, exporters.ErrExporterNotFound) {
-			slog.ErrorContext(ctx, "exporter type not found", slog.String("type", cfg.Type))
-			return
+		if _, ok := m.running[cfg.Name]; ok && errors.Is(err, exporters.ErrMetricConflict) {
+			return err
 		}
-		logger.ErrorContext(ctx, "failed to create exporter", slog.Any("err", err))
+		m.createFailed(cfg, logger, err)
+		return err
+	}
+
+	m.stop(cfg.Name)
+	m.start(cfg, logger, exporter, factory)
+	return nil
+}
+
+// replaceConflicting replaces the running exporters whose changed metrics conflict with the ones of other running exporters,
+// e.g. if several exporters change the buckets of a shared histogram.
+// All of them are stopped before any of them is created again, the exporters which still fail are started with their previous config.
+func (m *exporterManager) replaceConflicting(configs []exporters.Config) error {
+	previous := make(map[string]exporters.Config, len(configs))
+	for _, cfg := range configs {
+		previous[cfg.Name] = m.running[cfg.Name].cfg
+		m.stop(cfg.Name)
+	}
+
+	var (
+		errs   []error
+		failed []exporters.Config
+	)
+	for _, cfg := range configs {
+		logger := exporterLogger(cfg)
+		exporter, factory, err := m.create(cfg, logger)
+		if err != nil {
+			logger.ErrorContext(m.ctx, "failed to create exporter", slog.Any("err", err))
+			errs = append(errs, fmt.Errorf("exporter %q: %w", cfg.Name, err))
+			failed = append(failed, cfg)
+			continue
+		}
+		m.start(cfg, logger, exporter, factory)
+	}
+	for _, cfg := range failed {
+		m.restart(cfg, previous[cfg.Name])
+	}
+	return errors.Join(errs...)
+}
+
+// restart starts the exporter with the previous config again after the new config failed.
+func (m *exporterManager) restart(cfg exporters.Config, previous exporters.Config) {
+	logger := exporterLogger(previous)
+	exporter, factory, err := m.create(previous, logger)
+	if err != nil {
+		logger.ErrorContext(m.ctx, "failed to create exporter with the previous config", slog.Any("err", err))
+		m.failedStatus(cfg, err)
 		return
 	}
+	logger.WarnContext(m.ctx, "started exporter with the previous config")
+	m.start(previous, logger, exporter, factory)
+}
+
+// createFailed logs the error and keeps the running exporter with the previous config, or records the error in the status.
+func (m *exporterManager) createFailed(cfg exporters.Config, logger *slog.Logger, err error) {
+	if errors.Is(err, exporters.ErrExporterNotFound) {
+		slog.ErrorContext(m.ctx, "exporter type not found", slog.String("name", cfg.Name), slog.String("type", cfg.Type))
+	} else {
+		logger.ErrorContext(m.ctx, "failed to create exporter", slog.Any("err", err))
+	}
+	if _, ok := m.running[cfg.Name]; ok {
+		logger.WarnContext(m.ctx, "keeping the running exporter with the previous config")
+		return
+	}
+	m.failedStatus(cfg, err)
+}
+
+// failedStatus adds the status of an exporter which isn't running because it couldn't be created.
+func (m *exporterManager) failedStatus(cfg exporters.Config, err error) {
+	m.status.add(cfg)
+	m.status.update(cfg.Name, func(s *exporterStatus) {
+		s.LastError = "create exporter: " + err.Error()
+	})
+}
+
+// create creates the exporter and its metrics, it doesn't collect until it is started.
+func (m *exporterManager) create(cfg exporters.Config, logger *slog.Logger) (exporters.Exporter, *exporters.MetricFactory, error) {
+	factory := m.registry.Factory()
+	exporter, err := exporters.New(cfg, logger, factory)
+	if err != nil {
+		factory.Close()
+		return nil, nil, err
+	}
+	return exporter, factory, nil
+}
+
+// Shutdown stops scheduling new collections of all running exporters and waits for the collections in flight to finish until ctx is done.
+// The collections which are still running then are aborted and the names of their exporters are returned.
+// Aborted exporters get abortGracePeriod to close, the ones still running after it are not closed.
+func (m *exporterManager) Shutdown(ctx context.Context) []string {
+	for _, running := range m.running {
+		running.cancel()
+	}
+
+	var pending []string
+	for name, running := range m.running {
+		select {
+		case <-running.done:
+		case <-ctx.Done():
+		}
+		select {
+		case <-running.done:
+			delete(m.running, name)
+		default:
+			running.abort()
+			pending = append(pending, name)
+		}
+	}
+	slices.Sort(pending)
+
+	graceCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), abortGracePeriod)
+	defer cancel()
+	var unclosed []string
+	for _, name := range pending {
+		select {
+		case <-m.running[name].done:
+		case <-graceCtx.Done():
+		}
+		select {
+		case <-m.running[name].done:
+			delete(m.running, name)
+		default:
+			unclosed = append(unclosed, name)
+		}
+	}
+	if len(unclosed) > 0 {
+		slog.ErrorContext(ctx, "aborted exporters failed to close", slog.Any("exporters", unclosed), slog.Duration("grace_period", abortGracePeriod))
+	}
+	return pending
+}
+
+func (m *exporterManager) start(cfg exporters.Config, logger *slog.Logger, exporter exporters.Exporter, factory *exporters.MetricFactory) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	// collections are not canceled with the scheduling, so they can finish on shutdown
+	collectCtx, abort := context.WithCancel(context.WithoutCancel(m.ctx))
+	running := &runningExporter{
+		cfg:    cfg,
+		cancel: cancel,
+		abort:  abort,
+		done:   make(chan struct{}),
+	}
+	m.running[cfg.Name] = running
+	m.status.add(cfg)
+
+	go func() {
+		defer close(running.done)
+		defer abort()
+		collect(ctx, collectCtx, logger, cfg, exporter, factory, m.limiter, m.status, m.onDemand)
+	}()
+}
+
+// stop stops the exporter right away, including its collection in flight.
+func (m *exporterManager) stop(name string) {
+	running, ok := m.running[name]
+	if !ok {
+		return
+	}
+	running.cancel()
+	running.abort()
+	<-running.done
+	delete(m.running, name)
+	m.status.remove(name)
+}
+
+func exporterLogger(cfg exporters.Config) *slog.Logger {
+	return slog.With(
+		slog.String("name", cfg.Name),
+		slog.String("type", cfg.Type),
+		slog.Duration("interval", time.Duration(cfg.Interval)),
+		slog.Duration("timeout", time.Duration(cfg.Timeout)),
+	)
+}
+
+// collect schedules the collections of the exporter until ctx is done and waits for the collection in flight, which runs with collectCtx.
+func collect(ctx context.Context, collectCtx context.Context, logger *slog.Logger, cfg exporters.Config, exporter exporters.Exporter, factory *exporters.MetricFactory, limiter *scrapeLimiter, status *statusStore, onDemand *onDemandCollectors) {
+	slog.DebugContext(ctx, "starting exporter", slog.String("name", cfg.Name))
+
+	// closed after the exporter, removes the series and metrics of the exporter
+	defer factory.Close()
 	defer func() {
 		if closeErr := exporter.Close(); closeErr != nil {
 			logger.ErrorContext(ctx, "failed to close exporter", slog.Any("err", closeErr))
 		}
+		labels := prometheus.Labels{"name": cfg.Name}
+		scrapeSuccess.DeletePartialMatch(labels)
+		scrapeDuration.DeletePartialMatch(labels)
+		lastSuccessTimestamp.DeletePartialMatch(labels)
+		scrapeErrors.DeletePartialMatch(labels)
+		metricErrors.DeletePartialMatch(labels)
+		scrapeSkipped.DeletePartialMatch(labels)
+		circuitBreakerState.DeletePartialMatch(labels)
 	}()
 
-	timer := time.NewTicker(time.Duration(cfg.Interval))
+	state := newScrapeState(factory, cfg, limiter, status)
+	if cfg.Mode == exporters.ModeOnDemand {
+		newOnDemandCollector(collectCtx, logger, cfg).run(ctx, onDemand, exporter, state)
+		return
+	}
+
+	// collect right away instead of leaving a gap of one interval after every start
+	at := time.Now()
+	schedule := newSchedule(cfg)
+	timer := time.NewTimer(0)
 	defer timer.Stop()
 
 	for {
@@ -64,13 +416,256 @@ func collect(ctx context.Context, logger *slog.Logger, cfg exporters.Config) {
 		case <-ctx.Done():
 			return
 		case <-timer.C:
-			doCollect(ctx, exporter, cfg)
+			state.collect(collectCtx, logger, exporter, cfg)
+
+			// collections which would have started while this one was running are skipped
+			now := time.Now()
+			if missed := schedule.missed(at, now); missed > 0 {
+				logger.DebugContext(ctx, "skipping collections missed while collecting", slog.Int("skipped", missed))
+				scrapeSkipped.WithLabelValues(cfg.Name, cfg.Type).Add(float64(missed))
+			}
+			at = state.next(schedule, now)
+			timer.Reset(time.Until(at))
+		}
+	}
+}
+
+func newScrapeState(factory *exporters.MetricFactory, cfg exporters.Config, limiter *scrapeLimiter, status *statusStore) *scrapeState {
+	s := &scrapeState{
+		factory:     factory,
+		limiter:     limiter,
+		status:      status,
+		lastSuccess: time.Now(),
+	}
+	s.setCircuit(cfg, circuitClosed)
+	return s
+}
+
+// scrapeState keeps track of the last successful scrape of an exporter to mark its series stale,
+// and of the consecutive failures for the circuit breaker and to only log the first failure and then periodic summaries.
+type scrapeState struct {
+	factory     *exporters.MetricFactory
+	limiter     *scrapeLimiter
+	status      *statusStore
+	lastSuccess time.Time
+	stale       bool
+
+	circuit      circuitState
+	failures     int
+	firstFailure time.Time
+	lastReason   string
+	// lastLog is the time the last failure was logged, failures in between are only counted in suppressed.
+	lastLog    time.Time
+	suppressed int
+	// delay is the backoff of the next collection while the circuit is open.
+	delay time.Duration
+	// successes is the number of consecutive successful collections.
+	successes int
+	// backoff is the number of failures since the circuit opened, it is kept after a success if the backoff doesn't reset on success.
+	backoff int
+	// failingMetrics are the comma separated names of the metrics which failed in the last collection.
+	failingMetrics string
+}
+
+// next returns the time of the next collection, while the circuit is open it is delayed by the backoff.
+func (s *scrapeState) next(schedule schedule, now time.Time) time.Time {
+	if s.circuit != circuitOpen || s.delay <= 0 {
+		return schedule.next(now)
+	}
+	return schedule.next(now.Add(s.delay))
+}
+
+func (s *scrapeState) collect(ctx context.Context, logger *slog.Logger, exporter exporters.Exporter, cfg exporters.Config) {
+	release, err := s.limiter.acquire(ctx)
+	if err != nil {
+		// the exporter is stopping
+		return
+	}
+	defer release()
+
+	if s.circuit == circuitOpen {
+		logger.DebugContext(ctx, "trying to collect with open circuit breaker", slog.Int("failures", s.failures))
+		s.setCircuit(cfg, circuitHalfOpen)
+	}
+
+	var values map[string]float64
+	collectCtx := exporters.WithValuesRecorder(ctx, func(v map[string]float64) {
+		values = v
+	})
+
+	start := time.Now()
+	duration, err := doCollect(collectCtx, exporter, cfg)
+	var partialErr *exporters.PartialError
+	if err == nil || errors.As(err, &partialErr) {
+		s.succeeded(ctx, logger, cfg)
+		s.metricsFailed(ctx, logger, partialErr)
+		s.updateStatus(cfg, values, start, duration, err)
+		return
+	}
+	if ctx.Err() != nil {
+		// the exporter is stopping
+		return
+	}
+	s.failed(ctx, logger, cfg, err)
+	s.updateStatus(cfg, nil, start, duration, err)
+
+	if cfg.StaleAfter > 0 && !s.stale && time.Since(s.lastSuccess) >= time.Duration(cfg.StaleAfter) {
+		logger.WarnContext(ctx, "marking exporter series stale", slog.String("mode", string(cfg.StaleMode)), slog.Time("last_success", s.lastSuccess))
+		s.factory.MarkStale(cfg.StaleMode)
+		s.stale = true
+	}
+}
+
+func (s *scrapeState) succeeded(ctx context.Context, logger *slog.Logger, cfg exporters.Config) {
+	if s.failures > 0 {
+		logger.InfoContext(ctx, "collected successfully after failures",
+			slog.Int("failures", s.failures),
+			slog.Duration("failing_for", time.Since(s.firstFailure)),
+			slog.String("circuit_breaker", string(s.circuit)),
+		)
+	}
+	s.setCircuit(cfg, circuitClosed)
+	s.failures = 0
+	s.lastReason = ""
+	s.suppressed = 0
+	s.delay = 0
+	s.successes++
+	if cfg.Backoff.Resets(s.successes) {
+		s.backoff = 0
+	}
+	s.lastSuccess = time.Now()
+	s.stale = false
+}
+
+// metricsFailed logs the metrics which failed in an otherwise successful collection,
+// a change of the failing metrics is logged as warning and the same failing metrics at debug level.
+func (s *scrapeState) metricsFailed(ctx context.Context, logger *slog.Logger, err *exporters.PartialError) {
+	var metrics []string
+	if err != nil {
+		for _, metricErr := range err.Errors {
+			metrics = append(metrics, metricErr.Metric)
+		}
+		slices.Sort(metrics)
+	}
+	failing := strings.Join(metrics, ",")
+
+	switch {
+	case failing == s.failingMetrics:
+		if err != nil {
+			logger.DebugContext(ctx, "failed to collect metrics", slog.Any("metrics", metrics), slog.Any("err", err))
+		}
+	case err == nil:
+		logger.InfoContext(ctx, "collected all metrics successfully after failures")
+	default:
+		logger.WarnContext(ctx, "failed to collect metrics", slog.Any("metrics", metrics), slog.Any("err", err))
+	}
+	s.failingMetrics = failing
+}
+
+func (s *scrapeState) failed(ctx context.Context, logger *slog.Logger, cfg exporters.Config, err error) {
+	now := time.Now()
+	reason := errorReason(err)
+	s.failures++
+	s.successes = 0
+	if s.failures == 1 {
+		s.firstFailure = now
+	}
+
+	switch {
+	case s.failures == 1 || reason != s.lastReason:
+		logger.ErrorContext(ctx, "failed to collect", slog.String("reason", reason), slog.Int("failures", s.failures), slog.Any("err", err))
+		s.lastLog = now
+		s.suppressed = 0
+	case now.Sub(s.lastLog) >= failureSummaryInterval:
+		logger.WarnContext(ctx, "still failing to collect",
+			slog.String("reason", reason),
+			slog.Int("failures", s.failures),
+			slog.Int("suppressed", s.suppressed),
+			slog.Duration("failing_for", now.Sub(s.firstFailure)),
+			slog.Any("err", err),
+		)
+		s.lastLog = now
+		s.suppressed = 0
+	default:
+		logger.DebugContext(ctx, "failed to collect", slog.String("reason", reason), slog.Int("failures", s.failures), slog.Any("err", err))
+		s.suppressed++
+	}
+	s.lastReason = reason
+
+	// without reset on success, the circuit opens again with the first failure until the backoff is reset
+	if s.failures < cfg.Backoff.Threshold && s.backoff == 0 {
+		return
+	}
+	s.delay = cfg.Backoff.Delay(s.backoff)
+	s.backoff++
+	if s.circuit == circuitClosed {
+		logger.WarnContext(ctx, "opening circuit breaker", slog.Int("failures", s.failures), slog.Duration("backoff", s.delay))
+	} else {
+		logger.DebugContext(ctx, "reopening circuit breaker", slog.Int("failures", s.failures), slog.Duration("backoff", s.delay))
+	}
+	s.setCircuit(cfg, circuitOpen)
+}
+
+func (s *scrapeState) setCircuit(cfg exporters.Config, state circuitState) {
+	s.circuit = state
+	for _, st := range circuitStates {
+		value := 0.0
+		if st == state {
+			value = 1
 		}
+		circuitBreakerState.WithLabelValues(cfg.Name, cfg.Type, string(st)).Set(value)
 	}
+	s.status.update(cfg.Name, func(status *exporterStatus) {
+		status.CircuitBreaker = string(state)
+	})
 }
 
-func doCollect(ctx context.Context, exporter exporters.Exporter, cfg exporters.Config) {
+// updateStatus records the collection started at start in the status store.
+// The values are only replaced if the collection recorded them.
+func (s *scrapeState) updateStatus(cfg exporters.Config, values map[string]float64, start time.Time, duration time.Duration, err error) {
+	if values != nil {
+		values = statusValues(values)
+	}
+	s.status.update(cfg.Name, func(status *exporterStatus) {
+		status.LastScrape = &start
+		status.LastDuration = duration.Seconds()
+		status.ConsecutiveFailures = s.failures
+		status.CircuitBreaker = string(s.circuit)
+		status.LastError = ""
+		if err != nil {
+			status.LastError = err.Error()
+		}
+		if values != nil {
+			status.Values = values
+		}
+	})
+}
+
+// doCollect collects the exporter once and returns the duration of the collection.
+// The error is a *exporters.PartialError if only some metrics failed.
+func doCollect(ctx context.Context, exporter exporters.Exporter, cfg exporters.Config) (time.Duration, error) {
 	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout))
 	defer cancel()
-	exporter.Collect(ctx)
+
+	start := time.Now()
+	err := exporter.Collect(ctx)
+	duration := time.Since(start)
+	scrapeDuration.WithLabelValues(cfg.Name, cfg.Type).Set(duration.Seconds())
+
+	// a collection in which only some metrics failed is still successful
+	var partialErr *exporters.PartialError
+	if err != nil && !errors.As(err, &partialErr) {
+		scrapeSuccess.WithLabelValues(cfg.Name, cfg.Type).Set(0)
+		scrapeErrors.WithLabelValues(cfg.Name, cfg.Type, errorReason(err)).Inc()
+		return duration, err
+	}
+	if partialErr != nil {
+		for _, metricErr := range partialErr.Errors {
+			metricErrors.WithLabelValues(cfg.Name, cfg.Type, metricErr.Metric).Inc()
+		}
+	}
+
+	scrapeSuccess.WithLabelValues(cfg.Name, cfg.Type).Set(1)
+	lastSuccessTimestamp.WithLabelValues(cfg.Name, cfg.Type).SetToCurrentTime()
+	return duration, err
 }
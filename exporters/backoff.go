@@ -0,0 +1,110 @@
+package exporters
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/topi314/prometheus-collectors/internal/xtime"
+)
+
+// DefaultBackoffConfig are the settings used if they are neither set in the exporter config nor in the global config.
+var DefaultBackoffConfig = BackoffConfig{
+	Threshold:      3,
+	Base:           ptr(xtime.Duration(30 * time.Second)),
+	Max:            xtime.Duration(10 * time.Minute),
+	ResetOnSuccess: ptr(true),
+}
+
+// BackoffConfig configures the circuit breaker of an exporter, which delays the collections of a failing exporter in timer mode.
+// Unset settings default to the settings of the global config and then to DefaultBackoffConfig.
+type BackoffConfig struct {
+	// Threshold is the number of consecutive failed collections after which the circuit breaker opens, 0 is unset.
+	Threshold int `toml:"threshold"`
+	// Base is the delay of the next collection after the circuit breaker opened, it doubles with every further failure. 0 disables the delay.
+	Base *xtime.Duration `toml:"base"`
+	// Max caps the delay, 0 is unset.
+	Max xtime.Duration `toml:"max"`
+	// ResetOnSuccess resets the delay with the first successful collection.
+	// Otherwise, a successful collection only closes the circuit breaker, the next failure opens it again with a doubled delay,
+	// and the delay is reset after threshold consecutive successful collections.
+	ResetOnSuccess *bool `toml:"reset_on_success"`
+}
+
+func (c BackoffConfig) Validate() error {
+	var errs []error
+	if c.Threshold < 0 {
+		errs = append(errs, errors.New("backoff config threshold must not be negative"))
+	}
+	if c.Base != nil && *c.Base < 0 {
+		errs = append(errs, errors.New("backoff config base must not be negative"))
+	}
+	if c.Max < 0 {
+		errs = append(errs, errors.New("backoff config max must not be negative"))
+	}
+	if c.Base != nil && c.Max > 0 && c.Max < *c.Base {
+		errs = append(errs, errors.New("backoff config max must not be less than base"))
+	}
+	return errors.Join(errs...)
+}
+
+// WithDefaults fills the unset settings with the settings of defaults.
+func (c BackoffConfig) WithDefaults(defaults BackoffConfig) BackoffConfig {
+	if c.Threshold == 0 {
+		c.Threshold = defaults.Threshold
+	}
+	if c.Base == nil {
+		c.Base = defaults.Base
+	}
+	if c.Max == 0 {
+		c.Max = defaults.Max
+	}
+	if c.ResetOnSuccess == nil {
+		c.ResetOnSuccess = defaults.ResetOnSuccess
+	}
+	return c
+}
+
+// Delay returns the delay of the next collection after the given number of failures since the circuit breaker opened.
+func (c BackoffConfig) Delay(failures int) time.Duration {
+	if c.Base == nil {
+		return 0
+	}
+	delay := time.Duration(*c.Base)
+	for range failures {
+		if delay >= time.Duration(c.Max) {
+			break
+		}
+		delay *= 2
+	}
+	return min(delay, time.Duration(c.Max))
+}
+
+// Resets reports whether the delay is reset after the given number of consecutive successful collections.
+func (c BackoffConfig) Resets(successes int) bool {
+	if c.ResetOnSuccess == nil || *c.ResetOnSuccess {
+		return successes > 0
+	}
+	return successes >= c.Threshold
+}
+
+func (c BackoffConfig) String() string {
+	return fmt.Sprintf("{threshold: %d, base: %s, max: %s, reset_on_success: %s}",
+		c.Threshold,
+		optionalString(c.Base, func(d xtime.Duration) string { return time.Duration(d).String() }),
+		time.Duration(c.Max).String(),
+		optionalString(c.ResetOnSuccess, strconv.FormatBool),
+	)
+}
+
+func optionalString[T any](v *T, format func(T) string) string {
+	if v == nil {
+		return "<unset>"
+	}
+	return format(*v)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
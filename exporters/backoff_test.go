@@ -0,0 +1,128 @@
+package exporters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/topi314/prometheus-collectors/internal/xtime"
+)
+
+func duration(d time.Duration) *xtime.Duration {
+	return ptr(xtime.Duration(d))
+}
+
+func TestBackoffConfigDelay(t *testing.T) {
+	cfg := BackoffConfig{
+		Threshold: 3,
+		Base:      duration(30 * time.Second),
+		Max:       xtime.Duration(5 * time.Minute),
+	}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 30 * time.Second},
+		{failures: 1, want: time.Minute},
+		{failures: 2, want: 2 * time.Minute},
+		{failures: 3, want: 4 * time.Minute},
+		{failures: 4, want: 5 * time.Minute},
+		{failures: 5, want: 5 * time.Minute},
+		{failures: 1000, want: 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+
+	disabled := BackoffConfig{Threshold: 3, Base: duration(0), Max: xtime.Duration(time.Minute)}
+	if got := disabled.Delay(5); got != 0 {
+		t.Errorf("Delay(5) with base 0 = %s, want 0", got)
+	}
+	if got := (BackoffConfig{}).Delay(5); got != 0 {
+		t.Errorf("Delay(5) without base = %s, want 0", got)
+	}
+}
+
+func TestBackoffConfigWithDefaults(t *testing.T) {
+	defaults := BackoffConfig{
+		Threshold:      3,
+		Base:           duration(30 * time.Second),
+		Max:            xtime.Duration(10 * time.Minute),
+		ResetOnSuccess: ptr(true),
+	}
+
+	tests := []struct {
+		name   string
+		config BackoffConfig
+		want   string
+	}{
+		{name: "unset", config: BackoffConfig{}, want: "{threshold: 3, base: 30s, max: 10m0s, reset_on_success: true}"},
+		{name: "base 0 is kept", config: BackoffConfig{Base: duration(0)}, want: "{threshold: 3, base: 0s, max: 10m0s, reset_on_success: true}"},
+		{name: "reset on success false is kept", config: BackoffConfig{ResetOnSuccess: ptr(false)}, want: "{threshold: 3, base: 30s, max: 10m0s, reset_on_success: false}"},
+		{
+			name:   "all set",
+			config: BackoffConfig{Threshold: 5, Base: duration(time.Second), Max: xtime.Duration(time.Minute), ResetOnSuccess: ptr(false)},
+			want:   "{threshold: 5, base: 1s, max: 1m0s, reset_on_success: false}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.WithDefaults(defaults).String(); got != tt.want {
+				t.Errorf("WithDefaults() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffConfigResets(t *testing.T) {
+	tests := []struct {
+		name      string
+		reset     *bool
+		successes int
+		want      bool
+	}{
+		{name: "default", reset: nil, successes: 1, want: true},
+		{name: "reset on success", reset: ptr(true), successes: 1, want: true},
+		{name: "no success", reset: ptr(true), successes: 0, want: false},
+		{name: "below threshold", reset: ptr(false), successes: 2, want: false},
+		{name: "at threshold", reset: ptr(false), successes: 3, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := BackoffConfig{Threshold: 3, ResetOnSuccess: tt.reset}
+			if got := cfg.Resets(tt.successes); got != tt.want {
+				t.Errorf("Resets(%d) = %t, want %t", tt.successes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  BackoffConfig
+		wantErr bool
+	}{
+		{name: "unset", config: BackoffConfig{}},
+		{name: "valid", config: BackoffConfig{Threshold: 3, Base: duration(time.Second), Max: xtime.Duration(time.Minute)}},
+		{name: "base 0", config: BackoffConfig{Base: duration(0)}},
+		{name: "max without base", config: BackoffConfig{Max: xtime.Duration(time.Minute)}},
+		{name: "negative threshold", config: BackoffConfig{Threshold: -1}, wantErr: true},
+		{name: "negative base", config: BackoffConfig{Base: duration(-time.Second)}, wantErr: true},
+		{name: "negative max", config: BackoffConfig{Max: xtime.Duration(-time.Second)}, wantErr: true},
+		{name: "max less than base", config: BackoffConfig{Base: duration(time.Minute), Max: xtime.Duration(time.Second)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package exporters
+
+import (
+	"maps"
+	"slices"
+)
+
+// conversions are the unit conversions which can be set with convert in a metric config.
+var conversions = map[string]func(float64) float64{
+	"f_to_c":      func(v float64) float64 { return (v - 32) * 5 / 9 },
+	"c_to_f":      func(v float64) float64 { return v*9/5 + 32 },
+	"k_to_c":      func(v float64) float64 { return v - 273.15 },
+	"c_to_k":      func(v float64) float64 { return v + 273.15 },
+	"inhg_to_hpa": func(v float64) float64 { return v * 33.8638866667 },
+	"hpa_to_inhg": func(v float64) float64 { return v / 33.8638866667 },
+	"mmhg_to_hpa": func(v float64) float64 { return v * 1.33322387415 },
+	"pa_to_hpa":   func(v float64) float64 { return v / 100 },
+	"mph_to_kmh":  func(v float64) float64 { return v * 1.609344 },
+	"kmh_to_mph":  func(v float64) float64 { return v / 1.609344 },
+	"ms_to_kmh":   func(v float64) float64 { return v * 3.6 },
+	"kmh_to_ms":   func(v float64) float64 { return v / 3.6 },
+	"in_to_mm":    func(v float64) float64 { return v * 25.4 },
+	"wh_to_kwh":   func(v float64) float64 { return v / 1000 },
+	"kwh_to_wh":   func(v float64) float64 { return v * 1000 },
+}
+
+func conversionNames() []string {
+	return slices.Sorted(maps.Keys(conversions))
+}
@@ -0,0 +1,98 @@
+package exporters
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		convert string
+		value   float64
+		want    float64
+	}{
+		{convert: "f_to_c", value: 212, want: 100},
+		{convert: "f_to_c", value: -40, want: -40},
+		{convert: "c_to_f", value: 37, want: 98.6},
+		{convert: "k_to_c", value: 0, want: -273.15},
+		{convert: "c_to_k", value: 20, want: 293.15},
+		{convert: "inhg_to_hpa", value: 29.92, want: 1013.2075},
+		{convert: "hpa_to_inhg", value: 1013.25, want: 29.9213},
+		{convert: "mmhg_to_hpa", value: 760, want: 1013.2501},
+		{convert: "pa_to_hpa", value: 101325, want: 1013.25},
+		{convert: "mph_to_kmh", value: 60, want: 96.5606},
+		{convert: "kmh_to_mph", value: 100, want: 62.1371},
+		{convert: "ms_to_kmh", value: 10, want: 36},
+		{convert: "kmh_to_ms", value: 36, want: 10},
+		{convert: "in_to_mm", value: 2, want: 50.8},
+		{convert: "wh_to_kwh", value: 1500, want: 1.5},
+		{convert: "kwh_to_wh", value: 1.5, want: 1500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.convert, func(t *testing.T) {
+			convert, ok := conversions[tt.convert]
+			if !ok {
+				t.Fatalf("conversion %s not found", tt.convert)
+			}
+			if got := convert(tt.value); math.Abs(got-tt.want) > 1e-4 {
+				t.Errorf("%s(%v) = %v, want %v", tt.convert, tt.value, got, tt.want)
+			}
+		})
+	}
+
+	if names := conversionNames(); len(names) != len(conversions) || !slices.IsSorted(names) {
+		t.Errorf("conversionNames() = %v, want all %d conversions sorted", names, len(conversions))
+	}
+}
+
+func TestMetricConfigApply(t *testing.T) {
+	scale := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name   string
+		config metricConfig
+		value  float64
+		want   float64
+	}{
+		{name: "unchanged", config: metricConfig{}, value: 21.5, want: 21.5},
+		{name: "offset", config: metricConfig{Offset: -0.7}, value: 21.5, want: 20.8},
+		{name: "scale", config: metricConfig{Scale: scale(0.1)}, value: 215, want: 21.5},
+		{name: "zero scale", config: metricConfig{Scale: scale(0)}, value: 215, want: 0},
+		{name: "scale and offset", config: metricConfig{Scale: scale(2), Offset: 1}, value: 3, want: 7},
+		{name: "convert before offset", config: metricConfig{Convert: "f_to_c", Offset: -0.7}, value: 212, want: 99.3},
+		{name: "convert before scale", config: metricConfig{Convert: "wh_to_kwh", Scale: scale(2)}, value: 1000, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.apply(tt.value); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("apply(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricConfigValidateUnit(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  metricConfig
+		wantErr bool
+	}{
+		{name: "no unit", config: metricConfig{Name: "temp"}},
+		{name: "unit suffix", config: metricConfig{Name: "temp_celsius", Unit: "celsius"}},
+		{name: "unit before total", config: metricConfig{Name: "energy_kwh_total", Type: metricTypeCounter, Unit: "kwh"}},
+		{name: "missing unit suffix", config: metricConfig{Name: "temp", Unit: "celsius"}, wantErr: true},
+		{name: "known convert", config: metricConfig{Name: "temp", Convert: "f_to_c"}},
+		{name: "unknown convert", config: metricConfig{Name: "temp", Convert: "f_to_k"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
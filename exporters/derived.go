@@ -0,0 +1,155 @@
+package exporters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/topi314/prometheus-collectors/internal/expr"
+)
+
+// DerivedMetricConfig is a metric computed by an expression over the values of the other metrics of the exporter,
+// e.g. dewpoint(temperature0, humidity). The available variables depend on the exporter type.
+type DerivedMetricConfig struct {
+	metricConfig
+	Expr expr.Expr `toml:"expr"`
+}
+
+func (c DerivedMetricConfig) Validate() error {
+	var errs []error
+	if err := c.metricConfig.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Expr.String() == "" {
+		errs = append(errs, errors.New("expr is required"))
+	}
+	return errors.Join(errs...)
+}
+
+func (c DerivedMetricConfig) String() string {
+	return fmt.Sprintf("%s\n  expr: %s",
+		c.metricConfig,
+		c.Expr,
+	)
+}
+
+// validateDerived validates the derived metrics and checks that their expressions only use variables of the exporter type.
+func (c Config) validateDerived() error {
+	if len(c.Derived) == 0 {
+		return nil
+	}
+	exporter, ok := exporters[c.Type]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrExporterNotFound, c.Type)
+	}
+	opts, err := exporter.options(c.Options)
+	if err != nil {
+		return err
+	}
+	variables := opts.variables()
+
+	var errs []error
+	for i, derived := range c.Derived {
+		if err = derived.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("derived[%d]: %w", i, err))
+			continue
+		}
+		for _, name := range derived.Expr.Vars() {
+			if !slices.Contains(variables, name) {
+				errs = append(errs, fmt.Errorf("derived[%d]: unknown variable %s in %q, must be one of %v", i, name, derived.Expr, variables))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// valueSource is implemented by exporters which provide the values of their last collection to derived metrics.
+type valueSource interface {
+	// values returns the values of the last collection by variable name.
+	values() map[string]float64
+}
+
+func newDerivedExporter(exporter Exporter, configs []DerivedMetricConfig, factory *MetricFactory) (Exporter, error) {
+	source, ok := exporter.(valueSource)
+	if !ok {
+		return nil, errors.New("exporter does not support derived metrics")
+	}
+
+	metrics := make([]derivedMetric, len(configs))
+	for i, cfg := range configs {
+		m, err := factory.metricFor(cfg.metricConfig)
+		if err != nil {
+			return nil, fmt.Errorf("create metric derived[%d]: %w", i, err)
+		}
+		metrics[i] = derivedMetric{
+			cfg:    cfg,
+			metric: m,
+		}
+	}
+
+	return &derivedExporter{
+		Exporter: exporter,
+		source:   source,
+		factory:  factory,
+		metrics:  metrics,
+	}, nil
+}
+
+type derivedMetric struct {
+	cfg    DerivedMetricConfig
+	metric *metric
+}
+
+// derivedExporter evaluates the derived metrics after each collection of the wrapped exporter which fetched the data.
+type derivedExporter struct {
+	Exporter
+	source  valueSource
+	factory *MetricFactory
+	metrics []derivedMetric
+	// lastValues are the values of the derived metrics of the last collection by metric name
+	lastValues map[string]float64
+}
+
+func (e *derivedExporter) Collect(ctx context.Context) error {
+	// derived metrics are still evaluated if only some metrics of the wrapped exporter failed
+	err := e.Exporter.Collect(ctx)
+	var partialErr *PartialError
+	if err != nil && !errors.As(err, &partialErr) {
+		return err
+	}
+	var errs []*MetricError
+	if partialErr != nil {
+		errs = append(errs, partialErr.Errors...)
+	}
+
+	values := e.source.values()
+	e.lastValues = make(map[string]float64, len(e.metrics))
+	for _, m := range e.metrics {
+		value, err := m.cfg.Expr.Eval(values)
+		if err == nil && (math.IsNaN(value) || math.IsInf(value, 0)) {
+			err = fmt.Errorf("result %g is not a number", value)
+		}
+		if err == nil {
+			value = m.cfg.apply(value)
+			e.lastValues[m.cfg.Name] = value
+			err = e.factory.observe(m.metric, m.cfg.Labels, value)
+		}
+		if err != nil {
+			errs = append(errs, &MetricError{Metric: m.cfg.Name, Err: err})
+		}
+	}
+	return partialError(errs)
+}
+
+// values returns the values of the wrapped exporter and of the derived metrics by metric name.
+func (e *derivedExporter) values() map[string]float64 {
+	values := maps.Clone(e.source.values())
+	if values == nil {
+		values = make(map[string]float64, len(e.lastValues))
+	}
+	maps.Copy(values, e.lastValues)
+	return values
+}
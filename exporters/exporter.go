@@ -5,30 +5,170 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/topi314/prometheus-collectors/internal/xtime"
+	"github.com/topi314/prometheus-collectors/internal/xtoml"
 )
 
-var ErrExporterNotFound = errors.New("exporter not found")
+var (
+	ErrExporterNotFound     = errors.New("exporter not found")
+	ErrUnexpectedStatusCode = errors.New("unexpected status code")
+	ErrDecode               = errors.New("failed to decode response")
+	ErrExtract              = errors.New("failed to extract value")
+	// ErrMetricConflict is returned when a metric is created with a different definition than the one of another exporter.
+	ErrMetricConflict = errors.New("metric conflict")
+	// ErrMetricChanged is returned when a metric is created with a different help or label names than it was registered with before.
+	// The prometheus.Registerer keeps them for its whole lifetime, so they can only change with a restart.
+	ErrMetricChanged = errors.New("metric help and label names can't change without a restart")
+)
+
+// MetricError is the failure to extract or set a single metric.
+type MetricError struct {
+	Metric string
+	Err    error
+}
+
+func (e *MetricError) Error() string {
+	return fmt.Sprintf("metric %s: %s", e.Metric, e.Err)
+}
+
+func (e *MetricError) Unwrap() []error {
+	return []error{ErrExtract, e.Err}
+}
+
+// PartialError is returned by Exporter.Collect if the data was fetched and decoded, but some metrics failed.
+// The other metrics were set, so the collection is still successful.
+type PartialError struct {
+	Errors []*MetricError
+}
+
+func (e *PartialError) Error() string {
+	errs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err.Error()
+	}
+	return strings.Join(errs, "\n")
+}
+
+func (e *PartialError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// partialError returns a *PartialError of the errors, or nil if there are none.
+func partialError(errs []*MetricError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &PartialError{Errors: errs}
+}
 
-var exporters = make(map[string]NewFunc)
+var exporters = make(map[string]exporterType)
 
-func Register(name string, new NewFunc) {
+type exporterType struct {
+	new     NewFunc
+	options OptionsFunc
+}
+
+func Register(name string, new NewFunc, options OptionsFunc) {
 	if _, ok := exporters[name]; ok {
 		panic("exporter already registered")
 	}
-	exporters[name] = new
+	exporters[name] = exporterType{
+		new:     new,
+		options: options,
+	}
 }
 
 type Configs []Config
 
 func (c Configs) Validate() error {
 	var errs []error
+	names := make(map[string]struct{}, len(c))
 	for i := range c {
 		if err := c[i].Validate(); err != nil {
 			errs = append(errs, err)
 		}
+		if _, ok := names[c[i].Name]; ok {
+			errs = append(errs, fmt.Errorf("exporter config %q is defined multiple times", c[i].Name))
+		}
+		names[c[i].Name] = struct{}{}
+	}
+	if err := c.validateMetricSchemas(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// validateMetricSchemas checks that all metrics with the same name have the same help, type, unit, buckets and label names across all configs.
+// Otherwise, the shared GaugeVec would panic when the series are set.
+func (c Configs) validateMetricSchemas() error {
+	type owner struct {
+		config string
+		schema metricSchema
+	}
+
+	var errs []error
+	owners := map[string]owner{}
+	for _, config := range c {
+		exporter, ok := exporters[config.Type]
+		if !ok {
+			continue
+		}
+		// invalid options are reported by Config.Validate
+		opts, err := exporter.options(config.Options)
+		if err != nil {
+			continue
+		}
+
+		schemas := opts.metrics()
+		for i, derived := range config.Derived {
+			schemas = append(schemas, derived.schema(fmt.Sprintf("derived[%d]", i)))
+		}
+		for _, schema := range schemas {
+			if schema.Name == "" {
+				continue
+			}
+			first, ok := owners[schema.Name]
+			if !ok {
+				owners[schema.Name] = owner{
+					config: config.Name,
+					schema: schema,
+				}
+				continue
+			}
+			if !slices.Equal(first.schema.Labels, schema.Labels) {
+				errs = append(errs, fmt.Errorf("metric %q has labels %v in exporter config %q (%s) but %v in exporter config %q (%s)",
+					schema.Name, first.schema.Labels, first.config, first.schema.Key, schema.Labels, config.Name, schema.Key,
+				))
+			}
+			if first.schema.Type != schema.Type {
+				errs = append(errs, fmt.Errorf("metric %q has type %s in exporter config %q (%s) but %s in exporter config %q (%s)",
+					schema.Name, first.schema.Type, first.config, first.schema.Key, schema.Type, config.Name, schema.Key,
+				))
+			}
+			if first.schema.Unit != schema.Unit {
+				errs = append(errs, fmt.Errorf("metric %q has unit %q in exporter config %q (%s) but %q in exporter config %q (%s)",
+					schema.Name, first.schema.Unit, first.config, first.schema.Key, schema.Unit, config.Name, schema.Key,
+				))
+			}
+			if !slices.Equal(first.schema.Buckets, schema.Buckets) {
+				errs = append(errs, fmt.Errorf("metric %q has buckets %v in exporter config %q (%s) but %v in exporter config %q (%s)",
+					schema.Name, first.schema.Buckets, first.config, first.schema.Key, schema.Buckets, config.Name, schema.Key,
+				))
+			}
+			if first.schema.Help != schema.Help {
+				errs = append(errs, fmt.Errorf("metric %q has help %q in exporter config %q (%s) but %q in exporter config %q (%s)",
+					schema.Name, first.schema.Help, first.config, first.schema.Key, schema.Help, config.Name, schema.Key,
+				))
+			}
+		}
 	}
 	return errors.Join(errs...)
 }
@@ -41,12 +181,42 @@ func (c Configs) String() string {
 	return str
 }
 
+type Mode string
+
+const (
+	// ModeTimer collects the data in the background every interval.
+	ModeTimer Mode = "timer"
+	// ModeOnDemand collects the data when the metrics are scraped.
+	ModeOnDemand Mode = "on_demand"
+)
+
+func (m Mode) Validate() error {
+	switch m {
+	case ModeTimer, ModeOnDemand:
+		return nil
+	default:
+		return fmt.Errorf("invalid mode %q, must be %s or %s", m, ModeTimer, ModeOnDemand)
+	}
+}
+
 type Config struct {
 	Name     string         `toml:"name"`
 	Type     string         `toml:"type"`
+	Mode     Mode           `toml:"mode"`
 	Interval xtime.Duration `toml:"interval"`
 	Timeout  xtime.Duration `toml:"timeout"`
-	Options  map[string]any `toml:"options"`
+	// Align collects at multiples of the interval, e.g. at the full minute, instead of at an offset derived from the name in timer mode.
+	Align bool `toml:"align"`
+	// CacheTTL is the duration the data collected in on_demand mode is reused for subsequent scrapes.
+	CacheTTL xtime.Duration `toml:"cache_ttl"`
+	// StaleAfter is the duration without a successful scrape after which the series of the exporter are marked stale.
+	StaleAfter xtime.Duration `toml:"stale_after"`
+	StaleMode  StaleMode      `toml:"stale_mode"`
+	// Backoff configures the circuit breaker, unset settings default to the global backoff config.
+	Backoff BackoffConfig  `toml:"backoff"`
+	Options map[string]any `toml:"options"`
+	// Derived are metrics computed from the values of the other metrics after each successful collection.
+	Derived []DerivedMetricConfig `toml:"derived"`
 }
 
 func (c Config) Validate() error {
@@ -57,34 +227,135 @@ func (c Config) Validate() error {
 	if c.Type == "" {
 		errs = append(errs, errors.New("exporter config type is required"))
 	}
+	if c.Mode != "" {
+		if err := c.Mode.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("exporter config mode: %w", err))
+		}
+	}
+	if c.StaleMode != "" {
+		if err := c.StaleMode.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("exporter config stale_mode: %w", err))
+		}
+	}
+	if err := c.Backoff.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("exporter config backoff: %w", err))
+	}
 	if len(c.Options) == 0 {
 		errs = append(errs, errors.New("exporter config options is required"))
+	} else if c.Type != "" {
+		if err := ValidateOptions(c.Type, c.Options); err != nil {
+			errs = append(errs, fmt.Errorf("exporter config options: %w", err))
+		} else if err = c.validateDerived(); err != nil {
+			errs = append(errs, fmt.Errorf("exporter config derived: %w", err))
+		}
 	}
 	return errors.Join(errs...)
 }
 
+// Warnings returns problems of the config which don't prevent the exporter from running.
+// The defaults of the global config must be applied to the config.
+func (c Config) Warnings() []string {
+	var warnings []string
+	if c.Mode != ModeOnDemand && c.Interval > 0 && c.Timeout >= c.Interval {
+		warnings = append(warnings, fmt.Sprintf("timeout %s is not less than interval %s, slow collections skip the following ones",
+			time.Duration(c.Timeout), time.Duration(c.Interval),
+		))
+	}
+	return warnings
+}
+
 func (c Config) String() string {
-	return fmt.Sprintf("\n  name: %s\n  type: %s\n  interval: %s\n  timeout: %s\n  options: %v",
+	return fmt.Sprintf("\n  name: %s\n  type: %s\n  mode: %s\n  interval: %s\n  timeout: %s\n  align: %t\n  cache_ttl: %s\n  stale_after: %s\n  stale_mode: %s\n  backoff: %s\n  options: %s\n  derived: %v",
 		c.Name,
 		c.Type,
+		c.Mode,
 		time.Duration(c.Interval).String(),
 		time.Duration(c.Timeout).String(),
-		c.Options,
+		c.Align,
+		time.Duration(c.CacheTTL).String(),
+		time.Duration(c.StaleAfter).String(),
+		c.StaleMode,
+		c.Backoff,
+		c.optionsString(),
+		c.Derived,
 	)
 }
 
-func New(cfg Config, logger *slog.Logger) (Exporter, error) {
-	newFunc, ok := exporters[cfg.Type]
+// New creates a new exporter of the configured type which creates its metrics with the given factory.
+// The factory must not be shared with other exporters and should be closed after the exporter to remove its metrics.
+func New(cfg Config, logger *slog.Logger, factory *MetricFactory) (Exporter, error) {
+	exporter, ok := exporters[cfg.Type]
 	if !ok {
 		return nil, ErrExporterNotFound
 	}
-	return newFunc(cfg, logger)
+	e, err := exporter.new(cfg, logger, factory)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Derived) > 0 {
+		if e, err = newDerivedExporter(e, cfg.Derived, factory); err != nil {
+			return nil, err
+		}
+	}
+	return newValuesExporter(e), nil
+}
+
+// optionsString formats the typed options of the exporter, so secrets in the raw options are never printed.
+func (c Config) optionsString() string {
+	exporter, ok := exporters[c.Type]
+	if !ok {
+		return "<unknown exporter type>"
+	}
+	opts, err := exporter.options(c.Options)
+	if err != nil {
+		return "<invalid options>"
+	}
+	return opts.String()
+}
+
+// ValidateOptions decodes and validates the options of the given exporter type without creating an exporter.
+func ValidateOptions(exporterType string, options map[string]any) error {
+	exporter, ok := exporters[exporterType]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrExporterNotFound, exporterType)
+	}
+	opts, err := exporter.options(options)
+	if err != nil {
+		return err
+	}
+	return opts.Validate()
+}
+
+// Options are the typed options of an exporter.
+// String must not print any secrets.
+type Options interface {
+	Validate() error
+	String() string
+	// metrics returns the schemas of the metrics the exporter creates.
+	metrics() []metricSchema
+	// variables returns the names of the values which can be used in the expressions of derived metrics.
+	variables() []string
+}
+
+// OptionsFunc decodes the raw options of an exporter into its typed options.
+type OptionsFunc func(options map[string]any) (Options, error)
+
+// decodeOptions is an OptionsFunc for the options type T.
+func decodeOptions[T Options](options map[string]any) (Options, error) {
+	var opts T
+	if err := xtoml.UnmarshalMap(options, &opts); err != nil {
+		return nil, fmt.Errorf("unmarshal options: %w", err)
+	}
+	return opts, nil
 }
 
-type NewFunc func(cfg Config, logger *slog.Logger) (Exporter, error)
+type NewFunc func(cfg Config, logger *slog.Logger, factory *MetricFactory) (Exporter, error)
 
 type Exporter interface {
-	Collect(ctx context.Context)
+	// Collect fetches the data from the device and updates the metrics.
+	// The returned error should wrap ErrUnexpectedStatusCode, ErrDecode or ErrExtract where applicable.
+	// If only some metrics failed, it returns a *PartialError.
+	Collect(ctx context.Context) error
 
 	Close() error
 }
@@ -0,0 +1,334 @@
+package exporters
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/topi314/prometheus-collectors/internal/secret"
+)
+
+type authType string
+
+const (
+	authTypeNone   authType = "none"
+	authTypeBasic  authType = "basic"
+	authTypeDigest authType = "digest"
+	authTypeBearer authType = "bearer"
+)
+
+// httpOptions are the options shared by all HTTP based exporters.
+type httpOptions struct {
+	// URL is the full URL of the device endpoint, Address and Insecure are kept for backward compatibility.
+	URL      string `toml:"url"`
+	Address  string `toml:"address"`
+	Insecure bool   `toml:"insecure"`
+	// Method is the HTTP method, defaults to GET.
+	Method  string            `toml:"method"`
+	Headers map[string]string `toml:"headers"`
+	Body    string            `toml:"body"`
+	// Auth is the authentication type, defaults to basic if a username and password are set and none otherwise.
+	Auth     authType      `toml:"auth"`
+	Username string        `toml:"username"`
+	Password secret.Secret `toml:"password"`
+	Token    secret.Secret `toml:"token"`
+	// PasswordFile and TokenFile are files containing the password/token, they are reloaded when they change.
+	PasswordFile string `toml:"password_file"`
+	TokenFile    string `toml:"token_file"`
+	// TLS configures the TLS client used for https requests.
+	TLS tlsOptions `toml:"tls"`
+}
+
+func (o httpOptions) authType() authType {
+	if o.Auth != "" {
+		return o.Auth
+	}
+	if o.Username != "" && (o.Password != "" || o.PasswordFile != "") {
+		return authTypeBasic
+	}
+	return authTypeNone
+}
+
+func (o httpOptions) url() string {
+	if o.URL != "" {
+		return o.URL
+	}
+	scheme := "https"
+	if o.Insecure {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s", scheme, o.Address)
+}
+
+// redactedURL returns the url with the password and the query values masked, as they may contain credentials.
+func (o httpOptions) redactedURL() string {
+	u, err := url.Parse(o.url())
+	if err != nil {
+		return "<invalid url>"
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query[key] = []string{"xxxxx"}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
+
+func (o httpOptions) method() string {
+	if o.Method == "" {
+		return http.MethodGet
+	}
+	return o.Method
+}
+
+func (o httpOptions) newRequest(ctx context.Context) (*http.Request, error) {
+	var body io.Reader
+	if o.Body != "" {
+		body = strings.NewReader(o.Body)
+	}
+
+	rq, err := http.NewRequestWithContext(ctx, o.method(), o.url(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range o.Headers {
+		if strings.EqualFold(name, "Host") {
+			rq.Host = value
+			continue
+		}
+		rq.Header.Set(name, value)
+	}
+	return rq, nil
+}
+
+func (o httpOptions) Validate() error {
+	var errs []error
+	switch {
+	case o.URL == "" && o.Address == "":
+		errs = append(errs, errors.New("url or address is required"))
+	case o.URL != "" && o.Address != "":
+		errs = append(errs, errors.New("url and address must not be set together"))
+	case o.URL != "":
+		u, err := url.Parse(o.URL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid url: %w", err))
+			break
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			errs = append(errs, fmt.Errorf("invalid url %q: scheme must be http or https", o.URL))
+		}
+		if u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid url %q: host is required", o.URL))
+		}
+	}
+	if _, err := http.NewRequest(o.method(), "http://localhost", nil); err != nil {
+		errs = append(errs, fmt.Errorf("invalid method %q", o.Method))
+	}
+	switch o.authType() {
+	case authTypeNone:
+	case authTypeBasic, authTypeDigest:
+		if o.Username == "" {
+			errs = append(errs, fmt.Errorf("username is required for %s auth", o.authType()))
+		}
+	case authTypeBearer:
+		if o.Token == "" && o.TokenFile == "" {
+			errs = append(errs, errors.New("token or token_file is required for bearer auth"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid auth %q, must be %s, %s, %s or %s", o.Auth, authTypeNone, authTypeBasic, authTypeDigest, authTypeBearer))
+	}
+	if o.Password != "" && o.PasswordFile != "" {
+		errs = append(errs, errors.New("password and password_file must not be set together"))
+	}
+	if o.Token != "" && o.TokenFile != "" {
+		errs = append(errs, errors.New("token and token_file must not be set together"))
+	}
+	for name, file := range map[string]string{"password_file": o.PasswordFile, "token_file": o.TokenFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	if err := o.TLS.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (o httpOptions) String() string {
+	return fmt.Sprintf("\n url: %s\n method: %s\n headers: %v\n body: %d bytes\n auth: %s\n username: %s\n password: %s\n password_file: %s\n token: %s\n token_file: %s\n tls: %s",
+		o.redactedURL(),
+		o.method(),
+		slices.Sorted(maps.Keys(o.Headers)),
+		// the body may contain credentials, e.g. of a login request
+		len(o.Body),
+		o.authType(),
+		o.Username,
+		o.Password,
+		o.PasswordFile,
+		o.Token,
+		o.TokenFile,
+		o.TLS,
+	)
+}
+
+func newHTTPClient(cfg Config, opts httpOptions) (*http.Client, error) {
+	transport, err := newTLSTransport(opts.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	authTransport, err := newAuthTransport(transport, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Timeout:   time.Duration(cfg.Timeout),
+		Transport: &recordingTransport{base: authTransport},
+	}, nil
+}
+
+type responseRecorderKey struct{}
+
+// ResponseRecorder is called with every response body received by the HTTP based exporters.
+type ResponseRecorder func(rq *http.Request, rs *http.Response, body []byte)
+
+// WithResponseRecorder returns a context which makes the HTTP based exporters pass the raw responses to record.
+func WithResponseRecorder(ctx context.Context, record ResponseRecorder) context.Context {
+	return context.WithValue(ctx, responseRecorderKey{}, record)
+}
+
+// recordingTransport passes the response bodies to the ResponseRecorder of the request context if there is one.
+type recordingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(rq *http.Request) (*http.Response, error) {
+	rs, err := t.base.RoundTrip(rq)
+	record, ok := rq.Context().Value(responseRecorderKey{}).(ResponseRecorder)
+	if err != nil || !ok {
+		return rs, err
+	}
+
+	body, err := io.ReadAll(rs.Body)
+	_ = rs.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	record(rq, rs, body)
+	rs.Body = io.NopCloser(bytes.NewReader(body))
+	return rs, nil
+}
+
+func (t *recordingTransport) CloseIdleConnections() {
+	closeIdleConnections(t.base)
+}
+
+func newAuthTransport(base http.RoundTripper, opts httpOptions) (http.RoundTripper, error) {
+	password, err := newSecretSource("password_file", opts.Password, opts.PasswordFile)
+	if err != nil {
+		return nil, err
+	}
+	token, err := newSecretSource("token_file", opts.Token, opts.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.authType() {
+	case authTypeBasic:
+		return &headerAuthTransport{
+			base: base,
+			authorize: func(rq *http.Request) error {
+				p, err := password()
+				if err != nil {
+					return err
+				}
+				rq.SetBasicAuth(opts.Username, p.Value())
+				return nil
+			},
+		}, nil
+	case authTypeBearer:
+		return &headerAuthTransport{
+			base: base,
+			authorize: func(rq *http.Request) error {
+				t, err := token()
+				if err != nil {
+					return err
+				}
+				rq.Header.Set("Authorization", "Bearer "+t.Value())
+				return nil
+			},
+		}, nil
+	case authTypeDigest:
+		return &digestTransport{
+			base:     base,
+			username: opts.Username,
+			password: password,
+		}, nil
+	default:
+		return base, nil
+	}
+}
+
+// newSecretSource returns a func returning the secret value or the trimmed content of the file if set.
+// The file is read once to report errors early and is reloaded when it changes.
+func newSecretSource(name string, value secret.Secret, file string) (func() (secret.Secret, error), error) {
+	if file == "" {
+		return func() (secret.Secret, error) {
+			return value, nil
+		}, nil
+	}
+
+	f := &reloadingFile[secret.Secret]{
+		paths: []string{file},
+		load: func() (secret.Secret, error) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return "", err
+			}
+			return secret.Secret(strings.TrimSpace(string(data))), nil
+		},
+	}
+	if _, err := f.get(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+	return f.get, nil
+}
+
+// headerAuthTransport sets the authorization header on every request.
+type headerAuthTransport struct {
+	base      http.RoundTripper
+	authorize func(rq *http.Request) error
+}
+
+func (t *headerAuthTransport) RoundTrip(rq *http.Request) (*http.Response, error) {
+	rq = rq.Clone(rq.Context())
+	if err := t.authorize(rq); err != nil {
+		return nil, fmt.Errorf("authorize request: %w", err)
+	}
+	return t.base.RoundTrip(rq)
+}
+
+func (t *headerAuthTransport) CloseIdleConnections() {
+	closeIdleConnections(t.base)
+}
+
+func closeIdleConnections(rt http.RoundTripper) {
+	if c, ok := rt.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
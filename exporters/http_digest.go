@@ -0,0 +1,240 @@
+package exporters
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/topi314/prometheus-collectors/internal/secret"
+)
+
+var errNoDigestChallenge = errors.New("no digest challenge in response")
+
+// digestTransport implements HTTP Digest authentication (RFC 7616) with qop=auth.
+// The last challenge is reused for subsequent requests until the server rejects its nonce.
+type digestTransport struct {
+	base     http.RoundTripper
+	username string
+	password func() (secret.Secret, error)
+
+	mu        sync.Mutex
+	challenge *digestChallenge
+	nc        uint32
+}
+
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+}
+
+func (t *digestTransport) RoundTrip(rq *http.Request) (*http.Response, error) {
+	if challenge, nc := t.nextNonceCount(); challenge != nil {
+		rs, err := t.roundTripWithChallenge(rq, challenge, nc)
+		if err != nil || rs.StatusCode != http.StatusUnauthorized {
+			return rs, err
+		}
+		return t.retryWithChallenge(rq, rs)
+	}
+
+	rs, err := t.base.RoundTrip(rq)
+	if err != nil || rs.StatusCode != http.StatusUnauthorized {
+		return rs, err
+	}
+	return t.retryWithChallenge(rq, rs)
+}
+
+func (t *digestTransport) CloseIdleConnections() {
+	closeIdleConnections(t.base)
+}
+
+// retryWithChallenge parses the challenge of the 401 response and retries the request once with it.
+func (t *digestTransport) retryWithChallenge(rq *http.Request, rs *http.Response) (*http.Response, error) {
+	challenge, err := parseDigestChallenge(rs.Header.Values("WWW-Authenticate"))
+	if err != nil {
+		// not a digest challenge, let the caller handle the 401
+		return rs, nil
+	}
+	_, _ = io.Copy(io.Discard, rs.Body)
+	_ = rs.Body.Close()
+
+	t.mu.Lock()
+	t.challenge = challenge
+	t.nc = 0
+	t.mu.Unlock()
+
+	retry := rq
+	if rq.Body != nil {
+		if rq.GetBody == nil {
+			return nil, errors.New("digest auth: cannot retry request with non-rewindable body")
+		}
+		body, err := rq.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("digest auth: get body: %w", err)
+		}
+		retry = rq.Clone(rq.Context())
+		retry.Body = body
+	}
+
+	challenge, nc := t.nextNonceCount()
+	return t.roundTripWithChallenge(retry, challenge, nc)
+}
+
+func (t *digestTransport) nextNonceCount() (*digestChallenge, uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.challenge == nil {
+		return nil, 0
+	}
+	t.nc++
+	return t.challenge, t.nc
+}
+
+func (t *digestTransport) roundTripWithChallenge(rq *http.Request, challenge *digestChallenge, nc uint32) (*http.Response, error) {
+	password, err := t.password()
+	if err != nil {
+		return nil, fmt.Errorf("digest auth: %w", err)
+	}
+	authorization, err := challenge.authorization(rq.Method, rq.URL.RequestURI(), t.username, password.Value(), nc)
+	if err != nil {
+		return nil, err
+	}
+	rq = rq.Clone(rq.Context())
+	rq.Header.Set("Authorization", authorization)
+	return t.base.RoundTrip(rq)
+}
+
+func (c *digestChallenge) authorization(method string, uri string, username string, password string, nc uint32) (string, error) {
+	var newHash func() hash.Hash
+	switch strings.ToUpper(strings.TrimSuffix(c.algorithm, "-sess")) {
+	case "", "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("digest auth: unsupported algorithm %q", c.algorithm)
+	}
+	h := func(s string) string {
+		hh := newHash()
+		hh.Write([]byte(s))
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+
+	cnonceBytes := make([]byte, 16)
+	if _, err := rand.Read(cnonceBytes); err != nil {
+		return "", fmt.Errorf("digest auth: generate cnonce: %w", err)
+	}
+	cnonce := hex.EncodeToString(cnonceBytes)
+	ncStr := fmt.Sprintf("%08x", nc)
+
+	ha1 := h(username + ":" + c.realm + ":" + password)
+	if strings.HasSuffix(strings.ToLower(c.algorithm), "-sess") {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	var response string
+	if c.qop == "auth" {
+		response = h(ha1 + ":" + c.nonce + ":" + ncStr + ":" + cnonce + ":" + c.qop + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`, username, c.realm, c.nonce, uri, response)
+	if c.algorithm != "" {
+		fmt.Fprintf(&sb, `, algorithm=%s`, c.algorithm)
+	}
+	if c.opaque != "" {
+		fmt.Fprintf(&sb, `, opaque="%s"`, c.opaque)
+	}
+	if c.qop == "auth" {
+		fmt.Fprintf(&sb, `, qop=%s, nc=%s, cnonce="%s"`, c.qop, ncStr, cnonce)
+	}
+	return sb.String(), nil
+}
+
+func parseDigestChallenge(headers []string) (*digestChallenge, error) {
+	for _, header := range headers {
+		scheme, params, ok := strings.Cut(strings.TrimSpace(header), " ")
+		if !ok || !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+
+		values := parseAuthParams(params)
+		challenge := &digestChallenge{
+			realm:     values["realm"],
+			nonce:     values["nonce"],
+			opaque:    values["opaque"],
+			algorithm: values["algorithm"],
+		}
+		if challenge.nonce == "" {
+			return nil, errors.New("digest auth: challenge without nonce")
+		}
+		if qop, ok := values["qop"]; ok {
+			qops := strings.Split(qop, ",")
+			for i := range qops {
+				qops[i] = strings.TrimSpace(qops[i])
+			}
+			if !slices.Contains(qops, "auth") {
+				return nil, fmt.Errorf("digest auth: unsupported qop %q", qop)
+			}
+			challenge.qop = "auth"
+		}
+		return challenge, nil
+	}
+	return nil, errNoDigestChallenge
+}
+
+// parseAuthParams parses comma separated key=value pairs where values may be quoted.
+func parseAuthParams(s string) map[string]string {
+	params := map[string]string{}
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ,")
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimSpace(rest)
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			var sb strings.Builder
+			i := 1
+			for ; i < len(rest); i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+					sb.WriteByte(rest[i])
+					continue
+				}
+				if rest[i] == '"' {
+					break
+				}
+				sb.WriteByte(rest[i])
+			}
+			value = sb.String()
+			s = rest[min(i+1, len(rest)):]
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end == -1 {
+				end = len(rest)
+			}
+			value = strings.TrimSpace(rest[:end])
+			s = rest[end:]
+		}
+		params[key] = value
+	}
+	return params
+}
@@ -0,0 +1,181 @@
+package exporters
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/topi314/prometheus-collectors/internal/secret"
+)
+
+// digestServer issues digest challenges with qop=auth and verifies the responses independently of digestTransport.
+type digestServer struct {
+	t         *testing.T
+	algorithm string
+	newHash   func() hash.Hash
+	username  string
+	password  string
+
+	mu        sync.Mutex
+	nonces    int
+	nonce     string
+	stale     bool
+	lastNC    map[string]uint64
+	requests  int
+	handshake int
+	ncs       []string
+}
+
+func (s *digestServer) h(v string) string {
+	hh := s.newHash()
+	hh.Write([]byte(v))
+	return hex.EncodeToString(hh.Sum(nil))
+}
+
+func (s *digestServer) challenge(w http.ResponseWriter, stale bool) {
+	s.nonces++
+	s.nonce = fmt.Sprintf("nonce-%d", s.nonces)
+	s.stale = false
+	s.handshake++
+	header := fmt.Sprintf(`Digest realm="test", qop="auth,auth-int", nonce="%s", opaque="opaque", algorithm=%s`, s.nonce, s.algorithm)
+	if stale {
+		header += ", stale=true"
+	}
+	w.Header().Set("WWW-Authenticate", header)
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+func (s *digestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	scheme, params, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || scheme != "Digest" {
+		s.challenge(w, false)
+		return
+	}
+	values := parseAuthParams(params)
+
+	if values["nonce"] != s.nonce || s.stale {
+		s.challenge(w, true)
+		return
+	}
+
+	var nc uint64
+	if _, err := fmt.Sscanf(values["nc"], "%08x", &nc); err != nil {
+		s.t.Errorf("invalid nc %q: %s", values["nc"], err)
+	}
+	if nc <= s.lastNC[values["nonce"]] {
+		s.t.Errorf("nc %d was not incremented, last nc was %d", nc, s.lastNC[values["nonce"]])
+	}
+	s.lastNC[values["nonce"]] = nc
+	s.ncs = append(s.ncs, values["nonce"]+"/"+values["nc"])
+
+	ha1 := s.h(s.username + ":test:" + s.password)
+	ha2 := s.h(r.Method + ":" + r.URL.RequestURI())
+	expected := s.h(ha1 + ":" + values["nonce"] + ":" + values["nc"] + ":" + values["cnonce"] + ":auth:" + ha2)
+
+	switch {
+	case values["username"] != s.username:
+		s.t.Errorf("username = %q, want %q", values["username"], s.username)
+	case values["qop"] != "auth":
+		s.t.Errorf("qop = %q, want auth", values["qop"])
+	case values["opaque"] != "opaque":
+		s.t.Errorf("opaque = %q, want opaque", values["opaque"])
+	case values["algorithm"] != s.algorithm:
+		s.t.Errorf("algorithm = %q, want %q", values["algorithm"], s.algorithm)
+	case values["uri"] != r.URL.RequestURI():
+		s.t.Errorf("uri = %q, want %q", values["uri"], r.URL.RequestURI())
+	case values["response"] != expected:
+		s.t.Errorf("response = %q, want %q", values["response"], expected)
+	default:
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.WriteHeader(http.StatusForbidden)
+}
+
+func TestDigestTransport(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		newHash   func() hash.Hash
+	}{
+		{algorithm: "MD5", newHash: md5.New},
+		{algorithm: "SHA-256", newHash: sha256.New},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			server := &digestServer{
+				t:         t,
+				algorithm: tt.algorithm,
+				newHash:   tt.newHash,
+				username:  "user",
+				password:  "pass",
+				lastNC:    map[string]uint64{},
+			}
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+
+			client := &http.Client{
+				Transport: &digestTransport{
+					base:     http.DefaultTransport,
+					username: "user",
+					password: func() (secret.Secret, error) {
+						return "pass", nil
+					},
+				},
+			}
+			get := func() {
+				t.Helper()
+				rs, err := client.Get(ts.URL + "/data?x=1")
+				if err != nil {
+					t.Fatalf("get: %s", err)
+				}
+				_ = rs.Body.Close()
+				if rs.StatusCode != http.StatusOK {
+					t.Fatalf("status = %d, want %d", rs.StatusCode, http.StatusOK)
+				}
+			}
+
+			// the first request does the handshake
+			get()
+			// the nonce is reused with an incremented nc
+			get()
+			get()
+
+			// the server rejects the nonce as stale, the transport must do a new handshake
+			server.mu.Lock()
+			server.stale = true
+			server.mu.Unlock()
+			get()
+			get()
+
+			want := []string{
+				"nonce-1/00000001",
+				"nonce-1/00000002",
+				"nonce-1/00000003",
+				"nonce-2/00000001",
+				"nonce-2/00000002",
+			}
+			if fmt.Sprint(server.ncs) != fmt.Sprint(want) {
+				t.Errorf("nonce counts = %v, want %v", server.ncs, want)
+			}
+			if server.handshake != 2 {
+				t.Errorf("handshakes = %d, want 2", server.handshake)
+			}
+			// 2 requests per handshake and 3 reusing the nonce
+			if server.requests != 7 {
+				t.Errorf("requests = %d, want 7", server.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package exporters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/topi314/prometheus-collectors/internal/jsonpath"
+	"github.com/topi314/prometheus-collectors/internal/xtoml"
+)
+
+const HTTPJSONType = "http-json"
+
+func init() {
+	Register(HTTPJSONType, newHTTPJSON, decodeOptions[httpJSONGenericOptions])
+}
+
+func newHTTPJSON(cfg Config, logger *slog.Logger, factory *MetricFactory) (Exporter, error) {
+	var opts httpJSONGenericOptions
+	if err := xtoml.UnmarshalMap(cfg.Options, &opts); err != nil {
+		return nil, fmt.Errorf("unmarshal http json options: %w", err)
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("validate http json options: %w", err)
+	}
+
+	client, err := newHTTPClient(cfg, opts.httpOptions)
+	if err != nil {
+		return nil, fmt.Errorf("create http client: %w", err)
+	}
+
+	metrics := make([]httpJSONMetric, len(opts.Metrics))
+	for i, metric := range opts.Metrics {
+		m, err := factory.metricFor(metric.metricConfig, slices.Collect(maps.Keys(metric.LabelPaths))...)
+		if err != nil {
+			return nil, fmt.Errorf("create metric metrics[%d]: %w", i, err)
+		}
+		metrics[i] = httpJSONMetric{
+			metric: m,
+			series: map[string]prometheus.Labels{},
+		}
+	}
+
+	return &httpJSONExporter{
+		factory: factory,
+		opts:    opts,
+		logger:  logger,
+		metrics: metrics,
+		client:  client,
+	}, nil
+}
+
+type httpJSONMetric struct {
+	metric *metric
+	// series holds the label sets set during the last collection, keyed by labelsKey.
+	series map[string]prometheus.Labels
+}
+
+type httpJSONExporter struct {
+	factory *MetricFactory
+	opts    httpJSONGenericOptions
+	logger  *slog.Logger
+	metrics []httpJSONMetric
+	client  *http.Client
+	// lastValues are the values of the last collection of the metrics without items by metric name
+	lastValues map[string]float64
+}
+
+func (e *httpJSONExporter) Collect(ctx context.Context) error {
+	e.logger.DebugContext(ctx, "collecting http-json data")
+
+	rq, err := e.opts.newRequest(ctx)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	rs, err := e.client.Do(rq)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer func() {
+		if closeErr := rs.Body.Close(); closeErr != nil {
+			e.logger.Error("failed to close body", slog.Any("err", closeErr))
+		}
+	}()
+
+	if rs.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatusCode, rs.StatusCode)
+	}
+
+	var data any
+	if err = json.NewDecoder(rs.Body).Decode(&data); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+
+	// errors of single metrics don't fail the rest of the scrape
+	e.lastValues = make(map[string]float64, len(e.opts.Metrics))
+	var errs []*MetricError
+	for i, metric := range e.opts.Metrics {
+		if err = e.collectMetric(metric, &e.metrics[i], data); err != nil {
+			errs = append(errs, &MetricError{Metric: metric.Name, Err: err})
+		}
+	}
+	return partialError(errs)
+}
+
+func (e *httpJSONExporter) collectMetric(cfg httpJSONMetricConfig, metric *httpJSONMetric, data any) error {
+	var errs []error
+	elements := []any{data}
+	if cfg.Items.String() != "" {
+		// without items all series of the last collection are removed, like the series of a missing path
+		var err error
+		if elements, err = cfg.elements(data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	series := make(map[string]prometheus.Labels, len(elements))
+	for i, element := range elements {
+		labels, err := cfg.labels(element)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		key := labelsKey(labels)
+		if _, ok := series[key]; ok {
+			errs = append(errs, fmt.Errorf("items[%d]: duplicate labels %v of an earlier item", i, labels))
+			continue
+		}
+
+		f := 1.0
+		if cfg.metricType() != metricTypeInfo {
+			if f, err = cfg.value(element); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if err = e.factory.observe(metric.metric, labels, f); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		series[key] = labels
+		if cfg.Items.String() == "" && cfg.metricType() != metricTypeInfo {
+			e.lastValues[cfg.Name] = f
+		}
+	}
+
+	// remove series of elements which disappeared from the response
+	for key, labels := range metric.series {
+		if _, ok := series[key]; !ok {
+			e.factory.delete(metric.metric, labels)
+		}
+	}
+	metric.series = series
+	return errors.Join(errs...)
+}
+
+// elements returns the array at the items path.
+func (c httpJSONMetricConfig) elements(data any) ([]any, error) {
+	items, err := c.Items.Get(data)
+	if err != nil {
+		return nil, fmt.Errorf("items: %w", err)
+	}
+	arr, ok := items.([]any)
+	if !ok {
+		return nil, fmt.Errorf("items: %s is not an array", c.Items)
+	}
+	return arr, nil
+}
+
+func (e *httpJSONExporter) values() map[string]float64 {
+	return e.lastValues
+}
+
+func (e *httpJSONExporter) Close() error {
+	e.logger.Debug("closing http-json exporter")
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+func jsonFloat(v any) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse string value: %w", err)
+		}
+		return f, nil
+	case nil:
+		return 0, errors.New("value is null")
+	default:
+		return 0, fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+func jsonLabelValue(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case nil:
+		return "", errors.New("value is null")
+	default:
+		return "", fmt.Errorf("unsupported label value type %T", v)
+	}
+}
+
+type httpJSONGenericOptions struct {
+	Metrics []httpJSONMetricConfig `toml:"metrics"`
+
+	httpOptions
+}
+
+func (o httpJSONGenericOptions) Validate() error {
+	var errs []error
+	if err := o.httpOptions.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(o.Metrics) == 0 {
+		errs = append(errs, errors.New("at least one metric is required"))
+	}
+	for i, metric := range o.Metrics {
+		if err := metric.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("metrics[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (o httpJSONGenericOptions) metrics() []metricSchema {
+	schemas := make([]metricSchema, len(o.Metrics))
+	for i, metric := range o.Metrics {
+		schemas[i] = metric.schema(fmt.Sprintf("metrics[%d]", i), slices.Collect(maps.Keys(metric.LabelPaths))...)
+	}
+	return schemas
+}
+
+// variables are the names of the metrics with a single value, metrics with items or of type info can't be used.
+func (o httpJSONGenericOptions) variables() []string {
+	var variables []string
+	for _, metric := range o.Metrics {
+		if metric.Items.String() == "" && metric.metricType() != metricTypeInfo {
+			variables = append(variables, metric.Name)
+		}
+	}
+	return variables
+}
+
+func (o httpJSONGenericOptions) String() string {
+	return fmt.Sprintf("%s\n metrics: %v",
+		o.httpOptions,
+		o.Metrics,
+	)
+}
+
+type httpJSONMetricConfig struct {
+	metricConfig
+	// Path is the path to the metric value. If Items is set, it is relative to each array element.
+	Path jsonpath.Path `toml:"path"`
+	// Items is an optional path to an array, one series is emitted per array element.
+	Items jsonpath.Path `toml:"items"`
+	// LabelPaths maps label names to paths of their values. If Items is set, they are relative to each array element.
+	LabelPaths map[string]jsonpath.Path `toml:"label_paths"`
+}
+
+func (c httpJSONMetricConfig) labels(element any) (prometheus.Labels, error) {
+	labels := make(prometheus.Labels, len(c.Labels)+len(c.LabelPaths))
+	for name, value := range c.Labels {
+		labels[name] = value
+	}
+	for name, path := range c.LabelPaths {
+		v, err := path.Get(element)
+		if err != nil {
+			return nil, fmt.Errorf("label %s: %w", name, err)
+		}
+		value, err := jsonLabelValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("label %s: %w", name, err)
+		}
+		labels[name] = value
+	}
+	return labels, nil
+}
+
+// value returns the value at the path of the element with the conversions of the metric config applied.
+func (c httpJSONMetricConfig) value(element any) (float64, error) {
+	v, err := c.Path.Get(element)
+	if err != nil {
+		return 0, err
+	}
+	f, err := jsonFloat(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", c.Path, err)
+	}
+	return c.apply(f), nil
+}
+
+func (c httpJSONMetricConfig) Validate() error {
+	var errs []error
+	if err := c.metricConfig.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Path.String() == "" && c.metricType() != metricTypeInfo {
+		errs = append(errs, errors.New("path is required"))
+	}
+	for name := range c.LabelPaths {
+		if _, ok := c.Labels[name]; ok {
+			errs = append(errs, fmt.Errorf("label %s is defined in both labels and label_paths", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c httpJSONMetricConfig) String() string {
+	return fmt.Sprintf("%s\n  path: %s\n  items: %s\n  label_paths: %v",
+		c.metricConfig,
+		c.Path,
+		c.Items,
+		c.LabelPaths,
+	)
+}
@@ -7,11 +7,6 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"strings"
-	"time"
-
-	"github.com/prometheus/client_golang/prometheus"
-	"golang.org/x/exp/maps"
 
 	"github.com/topi314/prometheus-collectors/internal/xtoml"
 )
@@ -19,10 +14,10 @@ import (
 const HTTPJSONTempType = "http-json-temp"
 
 func init() {
-	Register(HTTPJSONTempType, newHTTPJSONTemp)
+	Register(HTTPJSONTempType, newHTTPJSONTemp, decodeOptions[httpJSONOptions])
 }
 
-func newHTTPJSONTemp(cfg Config, logger *slog.Logger) (Exporter, error) {
+func newHTTPJSONTemp(cfg Config, logger *slog.Logger, factory *MetricFactory) (Exporter, error) {
 	var opts httpJSONOptions
 	if err := xtoml.UnmarshalMap(cfg.Options, &opts); err != nil {
 		return nil, fmt.Errorf("unmarshal http json temp options: %w", err)
@@ -32,62 +27,58 @@ func newHTTPJSONTemp(cfg Config, logger *slog.Logger) (Exporter, error) {
 		return nil, fmt.Errorf("validate http json temp options: %w", err)
 	}
 
-	temperature0 := getOrCreateGauge(prometheus.GaugeOpts{
-		Name: opts.Metrics.Temperature0.Name,
-		Help: opts.Metrics.Temperature0.Help,
-	}, maps.Keys(opts.Metrics.Temperature0.Labels))
+	client, err := newHTTPClient(cfg, opts.httpOptions)
+	if err != nil {
+		return nil, fmt.Errorf("create http client: %w", err)
+	}
 
-	temperature1 := getOrCreateGauge(prometheus.GaugeOpts{
-		Name: opts.Metrics.Temperature1.Name,
-		Help: opts.Metrics.Temperature1.Help,
-	}, maps.Keys(opts.Metrics.Temperature1.Labels))
+	temperature0, err := factory.metricFor(opts.Metrics.Temperature0)
+	if err != nil {
+		return nil, fmt.Errorf("create metric temperature0: %w", err)
+	}
+
+	temperature1, err := factory.metricFor(opts.Metrics.Temperature1)
+	if err != nil {
+		return nil, fmt.Errorf("create metric temperature1: %w", err)
+	}
 
 	return &httpJSONTempExporter{
-		opts:   opts,
-		logger: logger,
-		gauges: httpJSONGauges{
+		factory: factory,
+		opts:    opts,
+		logger:  logger,
+		metrics: httpJSONTempMetrics{
 			temperature0: temperature0,
 			temperature1: temperature1,
-		}, client: &http.Client{
-			Timeout: time.Duration(cfg.Timeout),
-		},
+		}, client: client,
 	}, nil
 }
 
-type httpJSONGauges struct {
-	temperature0 *prometheus.GaugeVec
-	temperature1 *prometheus.GaugeVec
+type httpJSONTempMetrics struct {
+	temperature0 *metric
+	temperature1 *metric
 }
 
 type httpJSONTempExporter struct {
-	opts   httpJSONOptions
-	logger *slog.Logger
-	gauges httpJSONGauges
-	client *http.Client
+	factory *MetricFactory
+	opts    httpJSONOptions
+	logger  *slog.Logger
+	metrics httpJSONTempMetrics
+	client  *http.Client
+	// data is the data of the last collection
+	data jsonData
 }
 
-func (e *httpJSONTempExporter) Collect(ctx context.Context) {
+func (e *httpJSONTempExporter) Collect(ctx context.Context) error {
 	e.logger.DebugContext(ctx, "collecting http-json-temp data")
 
-	scheme := "https"
-	if e.opts.Insecure {
-		scheme = "http"
-	}
-
-	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s", scheme, e.opts.Address), nil)
+	rq, err := e.opts.newRequest(ctx)
 	if err != nil {
-		e.logger.Error("failed to create request", slog.Any("err", err))
-		return
-	}
-
-	if e.opts.Username != "" && e.opts.Password != "" {
-		rq.SetBasicAuth(e.opts.Username, e.opts.Password)
+		return fmt.Errorf("create request: %w", err)
 	}
 
 	rs, err := e.client.Do(rq)
 	if err != nil {
-		e.logger.Error("failed to do request", slog.Any("err", err))
-		return
+		return fmt.Errorf("do request: %w", err)
 	}
 	defer func() {
 		if closeErr := rs.Body.Close(); closeErr != nil {
@@ -96,18 +87,30 @@ func (e *httpJSONTempExporter) Collect(ctx context.Context) {
 	}()
 
 	if rs.StatusCode != http.StatusOK {
-		e.logger.Error("unexpected status code", slog.Any("code", rs.StatusCode))
-		return
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatusCode, rs.StatusCode)
 	}
 
 	var data jsonData
 	if err = json.NewDecoder(rs.Body).Decode(&data); err != nil {
-		e.logger.Error("failed to decode response", slog.Any("err", err))
-		return
+		return fmt.Errorf("%w: %w", ErrDecode, err)
 	}
 
-	e.gauges.temperature0.With(e.opts.Metrics.Temperature0.Labels).Set(data.Temperature0)
-	e.gauges.temperature1.With(e.opts.Metrics.Temperature1.Labels).Set(data.Temperature1)
+	data = data.apply(e.opts.Metrics)
+	e.data = data
+	if err = errors.Join(
+		e.factory.observe(e.metrics.temperature0, e.opts.Metrics.Temperature0.Labels, data.Temperature0),
+		e.factory.observe(e.metrics.temperature1, e.opts.Metrics.Temperature1.Labels, data.Temperature1),
+	); err != nil {
+		return fmt.Errorf("%w: %w", ErrExtract, err)
+	}
+	return nil
+}
+
+func (e *httpJSONTempExporter) values() map[string]float64 {
+	return map[string]float64{
+		"temperature0": e.data.Temperature0,
+		"temperature1": e.data.Temperature1,
+	}
 }
 
 type jsonData struct {
@@ -115,6 +118,14 @@ type jsonData struct {
 	Temperature1 float64 `json:"temperature1"`
 }
 
+// apply converts, scales and offsets the values according to the metric configs.
+func (d jsonData) apply(metrics httpJSONMetricsConfig) jsonData {
+	return jsonData{
+		Temperature0: metrics.Temperature0.apply(d.Temperature0),
+		Temperature1: metrics.Temperature1.apply(d.Temperature1),
+	}
+}
+
 func (e *httpJSONTempExporter) Close() error {
 	e.logger.Debug("closing http-json-temp exporter")
 	e.client.CloseIdleConnections()
@@ -124,25 +135,34 @@ func (e *httpJSONTempExporter) Close() error {
 type httpJSONOptions struct {
 	Metrics httpJSONMetricsConfig `toml:"metrics"`
 
-	Address  string `toml:"address"`
-	Insecure bool   `toml:"insecure"`
-	Username string `toml:"username"`
-	Password string `toml:"password"`
+	httpOptions
 }
 
 func (o httpJSONOptions) Validate() error {
-	if o.Address == "" {
-		return errors.New("address is required")
+	var errs []error
+	if err := o.httpOptions.Validate(); err != nil {
+		errs = append(errs, err)
 	}
-	return nil
+	if err := o.Metrics.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("metrics: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (o httpJSONOptions) metrics() []metricSchema {
+	return []metricSchema{
+		o.Metrics.Temperature0.schema("metrics.temperature0"),
+		o.Metrics.Temperature1.schema("metrics.temperature1"),
+	}
+}
+
+func (o httpJSONOptions) variables() []string {
+	return []string{"temperature0", "temperature1"}
 }
 
 func (o httpJSONOptions) String() string {
-	return fmt.Sprintf("\n address: %s\n insecure: %v\n username: %s\n password: %s\n metrics: %v",
-		o.Address,
-		o.Insecure,
-		o.Username,
-		strings.Repeat("*", len(o.Password)),
+	return fmt.Sprintf("%s\n metrics: %v",
+		o.httpOptions,
 		o.Metrics,
 	)
 }
@@ -0,0 +1,139 @@
+package exporters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/topi314/prometheus-collectors/internal/xtime"
+)
+
+func TestHTTPJSONCollect(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	registry := prometheus.NewRegistry()
+	cfg := Config{
+		Name:    "test",
+		Type:    HTTPJSONType,
+		Timeout: xtime.Duration(time.Second),
+		Options: map[string]any{
+			"url": ts.URL,
+			"metrics": []any{
+				map[string]any{
+					"name":        "sensor_temp",
+					"help":        "Temperature",
+					"items":       "$.sensors",
+					"path":        "$.temp",
+					"label_paths": map[string]any{"id": "$.id"},
+				},
+				map[string]any{
+					"name": "total",
+					"help": "Total",
+					"path": "$.total",
+				},
+			},
+		},
+	}
+	exporter, err := newHTTPJSON(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), NewRegistry(registry).Factory())
+	if err != nil {
+		t.Fatalf("newHTTPJSON() error = %v", err)
+	}
+	defer exporter.Close()
+
+	collect := func(data string) error {
+		t.Helper()
+		body = data
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		return exporter.Collect(ctx)
+	}
+	assertMetrics := func(want map[string]float64) {
+		t.Helper()
+		if got := gatherValues(t, registry); !maps.Equal(got, want) {
+			t.Errorf("metrics = %v, want %v", got, want)
+		}
+	}
+
+	if err = collect(`{"total": 2, "sensors": [{"id": "a", "temp": 20}, {"id": "b", "temp": 21}]}`); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	assertMetrics(map[string]float64{
+		`sensor_temp{id="a"}`: 20,
+		`sensor_temp{id="b"}`: 21,
+		`total{}`:             2,
+	})
+
+	// the value of b is missing and a is duplicated, the other metrics are still set
+	err = collect(`{"total": 3, "sensors": [{"id": "a", "temp": 22}, {"id": "b"}, {"id": "a", "temp": 23}]}`)
+	var partialErr *PartialError
+	if !errors.As(err, &partialErr) {
+		t.Fatalf("Collect() error = %v, want *PartialError", err)
+	}
+	if len(partialErr.Errors) != 1 || partialErr.Errors[0].Metric != "sensor_temp" {
+		t.Fatalf("Collect() errors = %v, want one error of sensor_temp", partialErr.Errors)
+	}
+	if !strings.Contains(err.Error(), "duplicate labels map[id:a]") {
+		t.Errorf("Collect() error = %v, want it to name the duplicate labels", err)
+	}
+	assertMetrics(map[string]float64{
+		`sensor_temp{id="a"}`: 22,
+		`total{}`:             3,
+	})
+
+	// b is added again once it has a value
+	if err = collect(`{"total": 4, "sensors": [{"id": "b", "temp": 24}]}`); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	assertMetrics(map[string]float64{
+		`sensor_temp{id="b"}`: 24,
+		`total{}`:             4,
+	})
+
+	// the series of the items are removed if the items are missing or not an array
+	for _, data := range []string{`{"total": 5}`, `{"total": 5, "sensors": {"id": "b", "temp": 25}}`} {
+		if err = collect(`{"total": 4, "sensors": [{"id": "b", "temp": 24}]}`); err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+		err = collect(data)
+		if !errors.As(err, &partialErr) || !strings.Contains(err.Error(), "items") {
+			t.Fatalf("Collect(%s) error = %v, want *PartialError of the items", data, err)
+		}
+		assertMetrics(map[string]float64{
+			`total{}`: 5,
+		})
+	}
+}
+
+// gatherValues returns the values of the gauges of the registry by metric name and labels.
+func gatherValues(t *testing.T, registry *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	values := map[string]float64{}
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			labels := make([]string, len(m.GetLabel()))
+			for i, label := range m.GetLabel() {
+				labels[i] = fmt.Sprintf("%s=%q", label.GetName(), label.GetValue())
+			}
+			values[family.GetName()+"{"+strings.Join(labels, ",")+"}"] = m.GetGauge().GetValue()
+		}
+	}
+	return values
+}
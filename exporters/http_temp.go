@@ -9,10 +9,6 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
-
-	"github.com/prometheus/client_golang/prometheus"
-	"golang.org/x/exp/maps"
 
 	"github.com/topi314/prometheus-collectors/internal/xtoml"
 )
@@ -20,10 +16,10 @@ import (
 const HTTPTempType = "http-temp"
 
 func init() {
-	Register(HTTPTempType, newHTTPTemp)
+	Register(HTTPTempType, newHTTPTemp, decodeOptions[httpTempOptions])
 }
 
-func newHTTPTemp(cfg Config, logger *slog.Logger) (Exporter, error) {
+func newHTTPTemp(cfg Config, logger *slog.Logger, factory *MetricFactory) (Exporter, error) {
 	var opts httpTempOptions
 	if err := xtoml.UnmarshalMap(cfg.Options, &opts); err != nil {
 		return nil, fmt.Errorf("unmarshal http temp options: %w", err)
@@ -33,50 +29,46 @@ func newHTTPTemp(cfg Config, logger *slog.Logger) (Exporter, error) {
 		return nil, fmt.Errorf("validate http temp options: %w", err)
 	}
 
-	gauge := getOrCreateGauge(prometheus.GaugeOpts{
-		Name: opts.Metric.Name,
-		Help: opts.Metric.Help,
-	}, maps.Keys(opts.Metric.Labels))
+	client, err := newHTTPClient(cfg, opts.httpOptions)
+	if err != nil {
+		return nil, fmt.Errorf("create http client: %w", err)
+	}
+
+	metric, err := factory.metricFor(opts.Metric)
+	if err != nil {
+		return nil, fmt.Errorf("create metric: %w", err)
+	}
 
 	return &httpTempExporter{
-		opts:   opts,
-		logger: logger,
-		gauge:  gauge,
-		client: &http.Client{
-			Timeout: time.Duration(cfg.Timeout),
-		},
+		factory: factory,
+		opts:    opts,
+		logger:  logger,
+		metric:  metric,
+		client:  client,
 	}, nil
 }
 
 type httpTempExporter struct {
-	opts   httpTempOptions
-	logger *slog.Logger
-	gauge  *prometheus.GaugeVec
-	client *http.Client
+	factory *MetricFactory
+	opts    httpTempOptions
+	logger  *slog.Logger
+	metric  *metric
+	client  *http.Client
+	// temp is the temperature of the last collection
+	temp float64
 }
 
-func (e *httpTempExporter) Collect(ctx context.Context) {
+func (e *httpTempExporter) Collect(ctx context.Context) error {
 	e.logger.DebugContext(ctx, "collecting http-temp data")
 
-	scheme := "https"
-	if e.opts.Insecure {
-		scheme = "http"
-	}
-
-	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s", scheme, e.opts.Address), nil)
+	rq, err := e.opts.newRequest(ctx)
 	if err != nil {
-		e.logger.Error("failed to create request", slog.Any("err", err))
-		return
-	}
-
-	if e.opts.Username != "" && e.opts.Password != "" {
-		rq.SetBasicAuth(e.opts.Username, e.opts.Password)
+		return fmt.Errorf("create request: %w", err)
 	}
 
 	rs, err := e.client.Do(rq)
 	if err != nil {
-		e.logger.Error("failed to do request", slog.Any("err", err))
-		return
+		return fmt.Errorf("do request: %w", err)
 	}
 	defer func() {
 		if closeErr := rs.Body.Close(); closeErr != nil {
@@ -85,23 +77,31 @@ func (e *httpTempExporter) Collect(ctx context.Context) {
 	}()
 
 	if rs.StatusCode != http.StatusOK {
-		e.logger.Error("unexpected status code", slog.Any("code", rs.StatusCode))
-		return
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatusCode, rs.StatusCode)
 	}
 
 	data, err := io.ReadAll(rs.Body)
 	if err != nil {
-		e.logger.Error("failed to read body", slog.Any("err", err))
-		return
+		return fmt.Errorf("read body: %w", err)
 	}
 
 	temp, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
 	if err != nil {
-		e.logger.Error("failed to parse temperature", slog.Any("err", err))
-		return
+		return fmt.Errorf("%w: parse temperature: %w", ErrDecode, err)
 	}
 
-	e.gauge.With(e.opts.Metric.Labels).Set(temp)
+	temp = e.opts.Metric.apply(temp)
+	e.temp = temp
+	if err = e.factory.observe(e.metric, e.opts.Metric.Labels, temp); err != nil {
+		return fmt.Errorf("%w: %w", ErrExtract, err)
+	}
+	return nil
+}
+
+func (e *httpTempExporter) values() map[string]float64 {
+	return map[string]float64{
+		"temperature": e.temp,
+	}
 }
 
 func (e *httpTempExporter) Close() error {
@@ -113,16 +113,13 @@ func (e *httpTempExporter) Close() error {
 type httpTempOptions struct {
 	Metric metricConfig `toml:"metric"`
 
-	Address  string `toml:"address"`
-	Insecure bool   `toml:"insecure"`
-	Username string `toml:"username"`
-	Password string `toml:"password"`
+	httpOptions
 }
 
 func (o httpTempOptions) Validate() error {
 	var errs []error
-	if o.Address == "" {
-		errs = append(errs, fmt.Errorf("address is required"))
+	if err := o.httpOptions.Validate(); err != nil {
+		errs = append(errs, err)
 	}
 	if err := o.Metric.Validate(); err != nil {
 		errs = append(errs, fmt.Errorf("metric: %w", err))
@@ -130,12 +127,17 @@ func (o httpTempOptions) Validate() error {
 	return errors.Join(errs...)
 }
 
+func (o httpTempOptions) metrics() []metricSchema {
+	return []metricSchema{o.Metric.schema("metric")}
+}
+
+func (o httpTempOptions) variables() []string {
+	return []string{"temperature"}
+}
+
 func (o httpTempOptions) String() string {
-	return fmt.Sprintf("\n address: %s\n insecure: %t\n username: %s\n password: %s\n metric: %s",
-		o.Address,
-		o.Insecure,
-		o.Username,
-		strings.Repeat("*", len(o.Password)),
+	return fmt.Sprintf("%s\n metric: %s",
+		o.httpOptions,
 		o.Metric,
 	)
 }
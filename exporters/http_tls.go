@@ -0,0 +1,186 @@
+package exporters
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+type tlsOptions struct {
+	CAFile             string `toml:"ca_file"`
+	CertFile           string `toml:"cert_file"`
+	KeyFile            string `toml:"key_file"`
+	ServerName         string `toml:"server_name"`
+	InsecureSkipVerify bool   `toml:"insecure_skip_verify"`
+	MinVersion         string `toml:"min_version"`
+}
+
+func (o tlsOptions) Validate() error {
+	var errs []error
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		errs = append(errs, errors.New("tls cert_file and key_file must be set together"))
+	}
+	if _, ok := tlsVersions[o.MinVersion]; o.MinVersion != "" && !ok {
+		errs = append(errs, fmt.Errorf("invalid tls min_version %q, must be 1.0, 1.1, 1.2 or 1.3", o.MinVersion))
+	}
+	return errors.Join(errs...)
+}
+
+func (o tlsOptions) String() string {
+	return fmt.Sprintf("{ca_file: %s, cert_file: %s, key_file: %s, server_name: %s, insecure_skip_verify: %t, min_version: %s}",
+		o.CAFile,
+		o.CertFile,
+		o.KeyFile,
+		o.ServerName,
+		o.InsecureSkipVerify,
+		o.MinVersion,
+	)
+}
+
+// newTLSTransport creates a http.RoundTripper using the TLS options.
+// The CA and client certificate files are checked for changes on every request/handshake and reloaded when they were modified.
+func newTLSTransport(opts tlsOptions) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+		MinVersion:         tlsVersions[opts.MinVersion],
+	}
+
+	if opts.CertFile != "" {
+		cert := &reloadingFile[tls.Certificate]{
+			paths: []string{opts.CertFile, opts.KeyFile},
+			load: func() (tls.Certificate, error) {
+				return tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+			},
+		}
+		if _, err := cert.get(); err != nil {
+			return nil, fmt.Errorf("load tls client certificate: %w", err)
+		}
+		transport.TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			c, err := cert.get()
+			if err != nil {
+				return nil, err
+			}
+			return &c, nil
+		}
+	}
+
+	if opts.CAFile == "" {
+		return transport, nil
+	}
+
+	ca := &reloadingFile[*x509.CertPool]{
+		paths: []string{opts.CAFile},
+		load: func() (*x509.CertPool, error) {
+			data, err := os.ReadFile(opts.CAFile)
+			if err != nil {
+				return nil, err
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+			}
+			return pool, nil
+		},
+	}
+	if _, err := ca.get(); err != nil {
+		return nil, fmt.Errorf("load tls ca: %w", err)
+	}
+
+	return &caReloadingTransport{
+		base: transport,
+		ca:   ca,
+	}, nil
+}
+
+// caReloadingTransport swaps the underlying transport when the CA file changed,
+// as the RootCAs of a transport can't be replaced once it is in use.
+type caReloadingTransport struct {
+	base *http.Transport
+	ca   *reloadingFile[*x509.CertPool]
+
+	mu      sync.Mutex
+	pool    *x509.CertPool
+	current *http.Transport
+}
+
+func (t *caReloadingTransport) RoundTrip(rq *http.Request) (*http.Response, error) {
+	pool, err := t.ca.get()
+	if err != nil {
+		return nil, fmt.Errorf("load tls ca: %w", err)
+	}
+
+	t.mu.Lock()
+	if pool != t.pool {
+		if t.current != nil {
+			t.current.CloseIdleConnections()
+		}
+		t.current = t.base.Clone()
+		t.current.TLSClientConfig.RootCAs = pool
+		t.pool = pool
+	}
+	current := t.current
+	t.mu.Unlock()
+
+	return current.RoundTrip(rq)
+}
+
+func (t *caReloadingTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current != nil {
+		t.current.CloseIdleConnections()
+	}
+}
+
+// reloadingFile caches the value loaded from files and reloads it when the modification time of one of the files changes.
+type reloadingFile[T any] struct {
+	paths []string
+	load  func() (T, error)
+
+	mu       sync.Mutex
+	value    T
+	modTimes []time.Time
+}
+
+func (f *reloadingFile[T]) get() (T, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	modTimes := make([]time.Time, len(f.paths))
+	for i, path := range f.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+
+	if f.modTimes != nil && slices.EqualFunc(f.modTimes, modTimes, time.Time.Equal) {
+		return f.value, nil
+	}
+
+	value, err := f.load()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	f.value = value
+	f.modTimes = modTimes
+	return value, nil
+}
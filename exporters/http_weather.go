@@ -7,11 +7,6 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"strings"
-	"time"
-
-	"github.com/prometheus/client_golang/prometheus"
-	"golang.org/x/exp/maps"
 
 	"github.com/topi314/prometheus-collectors/internal/xtoml"
 )
@@ -19,10 +14,10 @@ import (
 const HTTPWeather = "http-weather"
 
 func init() {
-	Register(HTTPWeather, newHTTPWeather)
+	Register(HTTPWeather, newHTTPWeather, decodeOptions[httpWeatherOptions])
 }
 
-func newHTTPWeather(cfg Config, logger *slog.Logger) (Exporter, error) {
+func newHTTPWeather(cfg Config, logger *slog.Logger, factory *MetricFactory) (Exporter, error) {
 	var opts httpWeatherOptions
 	if err := xtoml.UnmarshalMap(cfg.Options, &opts); err != nil {
 		return nil, fmt.Errorf("unmarshal http weather options: %w", err)
@@ -32,84 +27,80 @@ func newHTTPWeather(cfg Config, logger *slog.Logger) (Exporter, error) {
 		return nil, fmt.Errorf("validate http weather options: %w", err)
 	}
 
-	temperature0 := getOrCreateGauge(prometheus.GaugeOpts{
-		Name: opts.Metrics.Temperature0.Name,
-		Help: opts.Metrics.Temperature0.Help,
-	}, maps.Keys(opts.Metrics.Temperature0.Labels))
+	client, err := newHTTPClient(cfg, opts.httpOptions)
+	if err != nil {
+		return nil, fmt.Errorf("create http client: %w", err)
+	}
 
-	temperature1 := getOrCreateGauge(prometheus.GaugeOpts{
-		Name: opts.Metrics.Temperature1.Name,
-		Help: opts.Metrics.Temperature1.Help,
-	}, maps.Keys(opts.Metrics.Temperature1.Labels))
+	temperature0, err := factory.metricFor(opts.Metrics.Temperature0)
+	if err != nil {
+		return nil, fmt.Errorf("create metric temperature0: %w", err)
+	}
 
-	temperature2 := getOrCreateGauge(prometheus.GaugeOpts{
-		Name: opts.Metrics.Temperature2.Name,
-		Help: opts.Metrics.Temperature2.Help,
-	}, maps.Keys(opts.Metrics.Temperature2.Labels))
+	temperature1, err := factory.metricFor(opts.Metrics.Temperature1)
+	if err != nil {
+		return nil, fmt.Errorf("create metric temperature1: %w", err)
+	}
 
-	humidity := getOrCreateGauge(prometheus.GaugeOpts{
-		Name: opts.Metrics.Humidity.Name,
-		Help: opts.Metrics.Humidity.Help,
-	}, maps.Keys(opts.Metrics.Humidity.Labels))
+	temperature2, err := factory.metricFor(opts.Metrics.Temperature2)
+	if err != nil {
+		return nil, fmt.Errorf("create metric temperature2: %w", err)
+	}
 
-	pressure := getOrCreateGauge(prometheus.GaugeOpts{
-		Name: opts.Metrics.Pressure.Name,
-		Help: opts.Metrics.Pressure.Help,
-	}, maps.Keys(opts.Metrics.Pressure.Labels))
+	humidity, err := factory.metricFor(opts.Metrics.Humidity)
+	if err != nil {
+		return nil, fmt.Errorf("create metric humidity: %w", err)
+	}
+
+	pressure, err := factory.metricFor(opts.Metrics.Pressure)
+	if err != nil {
+		return nil, fmt.Errorf("create metric pressure: %w", err)
+	}
 
 	return &httpWeatherExporter{
-		opts:   opts,
-		logger: logger,
-		gauges: httpWeatherGauges{
+		factory: factory,
+		opts:    opts,
+		logger:  logger,
+		metrics: httpWeatherMetrics{
 			temperature0: temperature0,
 			temperature1: temperature1,
 			temperature2: temperature2,
 			humidity:     humidity,
 			pressure:     pressure,
 		},
-		client: &http.Client{
-			Timeout: time.Duration(cfg.Timeout),
-		},
+		client: client,
 	}, nil
 }
 
-type httpWeatherGauges struct {
-	temperature0 *prometheus.GaugeVec
-	temperature1 *prometheus.GaugeVec
-	temperature2 *prometheus.GaugeVec
-	humidity     *prometheus.GaugeVec
-	pressure     *prometheus.GaugeVec
+type httpWeatherMetrics struct {
+	temperature0 *metric
+	temperature1 *metric
+	temperature2 *metric
+	humidity     *metric
+	pressure     *metric
 }
 
 type httpWeatherExporter struct {
-	opts   httpWeatherOptions
-	logger *slog.Logger
-	gauges httpWeatherGauges
-	client *http.Client
+	factory *MetricFactory
+	opts    httpWeatherOptions
+	logger  *slog.Logger
+	metrics httpWeatherMetrics
+	client  *http.Client
+	// data is the data of the last collection
+	data weatherData
 }
 
-func (e *httpWeatherExporter) Collect(ctx context.Context) {
+func (e *httpWeatherExporter) Collect(ctx context.Context) error {
 	e.logger.DebugContext(ctx, "collecting http-temp data")
 
-	scheme := "https"
-	if e.opts.Insecure {
-		scheme = "http"
-	}
-
-	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s", scheme, e.opts.Address), nil)
+	rq, err := e.opts.newRequest(ctx)
 	if err != nil {
-		e.logger.Error("failed to create request", slog.Any("err", err))
-		return
-	}
-
-	if e.opts.Username != "" && e.opts.Password != "" {
-		rq.SetBasicAuth(e.opts.Username, e.opts.Password)
+		return fmt.Errorf("create request: %w", err)
 	}
 
 	rs, err := e.client.Do(rq)
 	if err != nil {
-		e.logger.Error("failed to do request", slog.Any("err", err))
-		return
+		return fmt.Errorf("do request: %w", err)
 	}
 	defer func() {
 		if closeErr := rs.Body.Close(); closeErr != nil {
@@ -118,21 +109,28 @@ func (e *httpWeatherExporter) Collect(ctx context.Context) {
 	}()
 
 	if rs.StatusCode != http.StatusOK {
-		e.logger.Error("unexpected status code", slog.Any("code", rs.StatusCode))
-		return
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatusCode, rs.StatusCode)
 	}
 
 	var data weatherData
 	if err = json.NewDecoder(rs.Body).Decode(&data); err != nil {
-		e.logger.Error("failed to decode response", slog.Any("err", err))
-		return
+		return fmt.Errorf("%w: %w", ErrDecode, err)
 	}
 
-	e.gauges.temperature0.With(e.opts.Metrics.Temperature0.Labels).Set(data.Temperature0)
-	e.gauges.temperature1.With(e.opts.Metrics.Temperature1.Labels).Set(data.Temperature1)
-	e.gauges.temperature2.With(e.opts.Metrics.Temperature2.Labels).Set(data.Temperature2)
-	e.gauges.humidity.With(e.opts.Metrics.Humidity.Labels).Set(data.Humidity)
-	e.gauges.pressure.With(e.opts.Metrics.Pressure.Labels).Set(data.Pressure)
+	data = data.apply(e.opts.Metrics)
+	e.data = data
+	var errs []*MetricError
+	observe := func(m *metric, cfg metricConfig, value float64) {
+		if err := e.factory.observe(m, cfg.Labels, value); err != nil {
+			errs = append(errs, &MetricError{Metric: cfg.Name, Err: err})
+		}
+	}
+	observe(e.metrics.temperature0, e.opts.Metrics.Temperature0, data.Temperature0)
+	observe(e.metrics.temperature1, e.opts.Metrics.Temperature1, data.Temperature1)
+	observe(e.metrics.temperature2, e.opts.Metrics.Temperature2, data.Temperature2)
+	observe(e.metrics.humidity, e.opts.Metrics.Humidity, data.Humidity)
+	observe(e.metrics.pressure, e.opts.Metrics.Pressure, data.Pressure)
+	return partialError(errs)
 }
 
 type weatherData struct {
@@ -143,6 +141,27 @@ type weatherData struct {
 	Pressure     float64 `json:"pressure"`
 }
 
+func (e *httpWeatherExporter) values() map[string]float64 {
+	return map[string]float64{
+		"temperature0": e.data.Temperature0,
+		"temperature1": e.data.Temperature1,
+		"temperature2": e.data.Temperature2,
+		"humidity":     e.data.Humidity,
+		"pressure":     e.data.Pressure,
+	}
+}
+
+// apply converts, scales and offsets the values according to the metric configs.
+func (d weatherData) apply(metrics httpWeatherMetricsConfig) weatherData {
+	return weatherData{
+		Temperature0: metrics.Temperature0.apply(d.Temperature0),
+		Temperature1: metrics.Temperature1.apply(d.Temperature1),
+		Temperature2: metrics.Temperature2.apply(d.Temperature2),
+		Humidity:     metrics.Humidity.apply(d.Humidity),
+		Pressure:     metrics.Pressure.apply(d.Pressure),
+	}
+}
+
 func (e *httpWeatherExporter) Close() error {
 	e.logger.Debug("closing http-weather exporter")
 	e.client.CloseIdleConnections()
@@ -152,25 +171,37 @@ func (e *httpWeatherExporter) Close() error {
 type httpWeatherOptions struct {
 	Metrics httpWeatherMetricsConfig `toml:"metrics"`
 
-	Address  string `toml:"address"`
-	Insecure bool   `toml:"insecure"`
-	Username string `toml:"username"`
-	Password string `toml:"password"`
+	httpOptions
 }
 
 func (o httpWeatherOptions) Validate() error {
-	if o.Address == "" {
-		return errors.New("address is required")
+	var errs []error
+	if err := o.httpOptions.Validate(); err != nil {
+		errs = append(errs, err)
 	}
-	return nil
+	if err := o.Metrics.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("metrics: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (o httpWeatherOptions) metrics() []metricSchema {
+	return []metricSchema{
+		o.Metrics.Temperature0.schema("metrics.temperature0"),
+		o.Metrics.Temperature1.schema("metrics.temperature1"),
+		o.Metrics.Temperature2.schema("metrics.temperature2"),
+		o.Metrics.Humidity.schema("metrics.humidity"),
+		o.Metrics.Pressure.schema("metrics.pressure"),
+	}
+}
+
+func (o httpWeatherOptions) variables() []string {
+	return []string{"temperature0", "temperature1", "temperature2", "humidity", "pressure"}
 }
 
 func (o httpWeatherOptions) String() string {
-	return fmt.Sprintf("\n address: %s\n insecure: %v\n username: %s\n password: %s\n metrics: %v",
-		o.Address,
-		o.Insecure,
-		o.Username,
-		strings.Repeat("*", len(o.Password)),
+	return fmt.Sprintf("%s\n metrics: %v",
+		o.httpOptions,
 		o.Metrics,
 	)
 }
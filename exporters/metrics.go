@@ -3,42 +3,474 @@ package exporters
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-var gaugeVecs = map[string]*prometheus.GaugeVec{}
+// NewRegistry creates a Registry which registers the metrics with the registerer.
+func NewRegistry(registerer prometheus.Registerer) *Registry {
+	return &Registry{
+		registerer: registerer,
+		metrics:    map[string]*sharedMetric{},
+		descs:      map[string]metricDesc{},
+	}
+}
+
+// Registry creates the metrics of exporters and registers them with its prometheus.Registerer.
+// Exporters creating a metric with the same name share it, it is unregistered once no exporter uses it anymore.
+type Registry struct {
+	registerer prometheus.Registerer
+
+	mu      sync.Mutex
+	metrics map[string]*sharedMetric
+	// descs are the help and label names of all metrics ever registered, they are kept after the metric is unregistered.
+	descs map[string]metricDesc
+}
+
+type metricDesc struct {
+	help   string
+	labels []string
+}
+
+type sharedMetric struct {
+	metric *metric
+	help   string
+	unit   string
+	labels []string
+	// buckets are the histogram buckets, nil for other types.
+	buckets []float64
+	refs    int
+}
+
+// Factory returns a new MetricFactory for a single exporter.
+func (r *Registry) Factory() *MetricFactory {
+	return &MetricFactory{
+		registry: r,
+		metrics:  map[string]*metric{},
+		series:   map[*metric]map[string]*trackedSeries{},
+	}
+}
+
+func (r *Registry) metric(c metricConfig, labelNames []string) (*metric, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	labels := slices.Sorted(slices.Values(labelNames))
+	if desc, ok := r.descs[c.Name]; ok {
+		if desc.help != c.Help {
+			return nil, fmt.Errorf("%w: metric %s was registered with help %q instead of %q", ErrMetricChanged, c.Name, desc.help, c.Help)
+		}
+		if !slices.Equal(desc.labels, labels) {
+			return nil, fmt.Errorf("%w: metric %s was registered with labels %v instead of %v", ErrMetricChanged, c.Name, desc.labels, labels)
+		}
+	}
+	if shared, ok := r.metrics[c.Name]; ok {
+		if shared.metric.typ != c.metricType() {
+			return nil, fmt.Errorf("%w: metric %s already exists with type %s instead of %s", ErrMetricConflict, c.Name, shared.metric.typ, c.metricType())
+		}
+		if !slices.Equal(shared.labels, labels) {
+			return nil, fmt.Errorf("%w: metric %s already exists with labels %v instead of %v", ErrMetricConflict, c.Name, shared.labels, labels)
+		}
+		if shared.help != c.Help {
+			return nil, fmt.Errorf("%w: metric %s already exists with help %q instead of %q", ErrMetricConflict, c.Name, shared.help, c.Help)
+		}
+		if shared.unit != c.Unit {
+			return nil, fmt.Errorf("%w: metric %s already exists with unit %q instead of %q", ErrMetricConflict, c.Name, shared.unit, c.Unit)
+		}
+		if !slices.Equal(shared.buckets, c.buckets()) {
+			return nil, fmt.Errorf("%w: metric %s already exists with buckets %v instead of %v", ErrMetricConflict, c.Name, shared.buckets, c.buckets())
+		}
+		shared.refs++
+		return shared.metric, nil
+	}
+
+	m := newMetric(c, labelNames)
+	if err := r.registerer.Register(m.collector()); err != nil {
+		return nil, fmt.Errorf("register metric %s: %w", c.Name, err)
+	}
+	r.metrics[c.Name] = &sharedMetric{
+		metric:  m,
+		help:    c.Help,
+		unit:    c.Unit,
+		labels:  labels,
+		buckets: c.buckets(),
+		refs:    1,
+	}
+	r.descs[c.Name] = metricDesc{
+		help:   c.Help,
+		labels: labels,
+	}
+	return m, nil
+}
 
-func getOrCreateGauge(opts prometheus.GaugeOpts, labelNames []string) *prometheus.GaugeVec {
-	name := prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name)
+// Units returns the units of the registered metrics which have a unit configured by metric name.
+func (r *Registry) Units() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	gauge, ok := gaugeVecs[name]
+	units := make(map[string]string)
+	for name, shared := range r.metrics {
+		if shared.unit != "" {
+			units[name] = shared.unit
+		}
+	}
+	return units
+}
+
+func (r *Registry) release(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	shared, ok := r.metrics[name]
 	if !ok {
-		gauge = promauto.NewGaugeVec(opts, labelNames)
-		gaugeVecs[name] = gauge
+		return
 	}
+	shared.refs--
+	if shared.refs > 0 {
+		return
+	}
+	r.registerer.Unregister(shared.metric.collector())
+	delete(r.metrics, name)
+}
+
+// MetricFactory creates the metrics of a single exporter and records the series the exporter has set,
+// so they can be marked stale when the exporter fails to collect and removed when the exporter is removed.
+type MetricFactory struct {
+	registry *Registry
 
-	return gauge
+	mu      sync.Mutex
+	metrics map[string]*metric
+	series  map[*metric]map[string]*trackedSeries
+}
+
+type trackedSeries struct {
+	labels prometheus.Labels
+	// last is the last value observed for counters, to detect resets of the device.
+	last float64
+}
+
+// metricFor returns the metric of the metric config with the label names of its constant labels and the additional label names.
+// It is created if no other exporter of the registry uses it yet.
+func (f *MetricFactory) metricFor(c metricConfig, labelNames ...string) (*metric, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if m, ok := f.metrics[c.Name]; ok {
+		return m, nil
+	}
+
+	m, err := f.registry.metric(c, append(slices.Collect(maps.Keys(c.Labels)), labelNames...))
+	if err != nil {
+		return nil, err
+	}
+	f.metrics[c.Name] = m
+	return m, nil
+}
+
+// observe records the value for the series according to the metric type:
+// gauges are set to the value, counters are increased by the difference to the last value,
+// histograms observe the value and info metrics are set to 1.
+func (f *MetricFactory) observe(m *metric, labels prometheus.Labels, value float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.series[m] == nil {
+		f.series[m] = map[string]*trackedSeries{}
+	}
+	key := labelsKey(labels)
+	series, ok := f.series[m][key]
+
+	switch m.typ {
+	case metricTypeCounter:
+		if value < 0 {
+			return fmt.Errorf("counter value %g must not be negative", value)
+		}
+		delta := value
+		// a lower value than the last one means the device reset its counter and started again from 0
+		if ok && value >= series.last {
+			delta = value - series.last
+		}
+		m.counter.With(labels).Add(delta)
+	case metricTypeHistogram:
+		m.histogram.With(labels).Observe(value)
+	case metricTypeInfo:
+		m.gauge.With(labels).Set(1)
+	default:
+		m.gauge.With(labels).Set(value)
+	}
+
+	if !ok {
+		series = &trackedSeries{labels: labels}
+		f.series[m][key] = series
+	}
+	series.last = value
+	return nil
+}
+
+func (f *MetricFactory) delete(m *metric, labels prometheus.Labels) {
+	m.delete(labels)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.series[m], labelsKey(labels))
+}
+
+// MarkStale deletes the series set by the exporter or sets them to NaN depending on the mode.
+// Counters and histograms can't be set to NaN and info series must be 1 or absent, they are always deleted.
+func (f *MetricFactory) MarkStale(mode StaleMode) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for m, series := range f.series {
+		for key, s := range series {
+			if mode == StaleModeNaN && m.gauge != nil && m.typ != metricTypeInfo {
+				m.gauge.With(s.labels).Set(math.NaN())
+				continue
+			}
+			m.delete(s.labels)
+			delete(series, key)
+		}
+		if len(series) == 0 {
+			delete(f.series, m)
+		}
+	}
+}
+
+// Close deletes all series set by the exporter and unregisters the metrics no other exporter of the registry uses.
+func (f *MetricFactory) Close() {
+	f.MarkStale(StaleModeDelete)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for name := range f.metrics {
+		f.registry.release(name)
+	}
+	clear(f.metrics)
+}
+
+type metricType string
+
+const (
+	metricTypeGauge     metricType = "gauge"
+	metricTypeCounter   metricType = "counter"
+	metricTypeHistogram metricType = "histogram"
+	metricTypeInfo      metricType = "info"
+)
+
+func (t metricType) Validate() error {
+	switch t {
+	case metricTypeGauge, metricTypeCounter, metricTypeHistogram, metricTypeInfo:
+		return nil
+	default:
+		return fmt.Errorf("invalid type %q, must be %s, %s, %s or %s", t, metricTypeGauge, metricTypeCounter, metricTypeHistogram, metricTypeInfo)
+	}
+}
+
+func newMetric(c metricConfig, labelNames []string) *metric {
+	m := &metric{
+		typ: c.metricType(),
+	}
+	switch m.typ {
+	case metricTypeCounter:
+		m.counter = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: c.Name,
+			Help: c.Help,
+		}, labelNames)
+	case metricTypeHistogram:
+		m.histogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    c.Name,
+			Help:    c.Help,
+			Buckets: c.buckets(),
+		}, labelNames)
+	default:
+		m.gauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: c.Name,
+			Help: c.Help,
+		}, labelNames)
+	}
+	return m
+}
+
+// metric is a metric of one of the metric types, exactly one of the vecs is set.
+// Info metrics are gauges which are always 1.
+type metric struct {
+	typ       metricType
+	gauge     *prometheus.GaugeVec
+	counter   *prometheus.CounterVec
+	histogram *prometheus.HistogramVec
+}
+
+func (m *metric) collector() prometheus.Collector {
+	switch {
+	case m.counter != nil:
+		return m.counter
+	case m.histogram != nil:
+		return m.histogram
+	default:
+		return m.gauge
+	}
+}
+
+func (m *metric) delete(labels prometheus.Labels) {
+	switch {
+	case m.counter != nil:
+		m.counter.Delete(labels)
+	case m.histogram != nil:
+		m.histogram.Delete(labels)
+	default:
+		m.gauge.Delete(labels)
+	}
 }
 
 type metricConfig struct {
 	Name   string            `toml:"name"`
 	Help   string            `toml:"help"`
 	Labels map[string]string `toml:"labels"`
+	// Type is the metric type, defaults to gauge.
+	Type metricType `toml:"type"`
+	// Buckets are the upper bounds of the histogram buckets, defaults to the Prometheus default buckets.
+	Buckets []float64 `toml:"buckets"`
+	// Convert converts the value between units, see conversions.
+	Convert string `toml:"convert"`
+	// Scale and Offset are applied after Convert as value * scale + offset.
+	Scale  *float64 `toml:"scale"`
+	Offset float64  `toml:"offset"`
+	// Unit is set as unit metadata of the metric, the name must end with _<unit>.
+	Unit string `toml:"unit"`
+}
+
+// apply converts, scales and offsets the value read from the device.
+func (c metricConfig) apply(value float64) float64 {
+	if convert, ok := conversions[c.Convert]; ok {
+		value = convert(value)
+	}
+	return value*c.scale() + c.Offset
+}
+
+func (c metricConfig) scale() float64 {
+	if c.Scale == nil {
+		return 1
+	}
+	return *c.Scale
+}
+
+// buckets returns the histogram buckets, which default to the Prometheus default buckets, and nil for other types.
+func (c metricConfig) buckets() []float64 {
+	if c.metricType() != metricTypeHistogram {
+		return nil
+	}
+	if len(c.Buckets) == 0 {
+		return prometheus.DefBuckets
+	}
+	return c.Buckets
+}
+
+func (c metricConfig) metricType() metricType {
+	if c.Type == "" {
+		return metricTypeGauge
+	}
+	return c.Type
 }
 
 func (c metricConfig) Validate() error {
+	var errs []error
 	if c.Name == "" {
-		return errors.New("metric config name is required")
+		errs = append(errs, errors.New("metric config name is required"))
 	}
-	return nil
+	if err := c.metricType().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("metric config %w", err))
+	}
+	if c.metricType() == metricTypeInfo && !strings.HasSuffix(c.Name, "_info") {
+		errs = append(errs, fmt.Errorf("metric config name %q of info metric must end with _info", c.Name))
+	}
+	if len(c.Buckets) > 0 {
+		if c.metricType() != metricTypeHistogram {
+			errs = append(errs, errors.New("metric config buckets are only supported for histograms"))
+		}
+		if !slices.IsSorted(c.Buckets) {
+			errs = append(errs, errors.New("metric config buckets must be sorted in increasing order"))
+		}
+	}
+	if _, ok := conversions[c.Convert]; c.Convert != "" && !ok {
+		errs = append(errs, fmt.Errorf("metric config convert %q is invalid, must be one of %v", c.Convert, conversionNames()))
+	}
+	if c.Unit != "" && !strings.HasSuffix(c.Name, "_"+c.Unit) && !strings.HasSuffix(c.Name, "_"+c.Unit+"_total") {
+		errs = append(errs, fmt.Errorf("metric config name %q must end with the unit _%s", c.Name, c.Unit))
+	}
+	return errors.Join(errs...)
 }
 
 func (c metricConfig) String() string {
-	return fmt.Sprintf("\n  name: %s\n  help: %s\n  labels: %v",
+	return fmt.Sprintf("\n  name: %s\n  help: %s\n  labels: %v\n  type: %s\n  buckets: %v\n  convert: %s\n  scale: %g\n  offset: %g\n  unit: %s",
 		c.Name,
 		c.Help,
 		c.Labels,
+		c.metricType(),
+		c.Buckets,
+		c.Convert,
+		c.scale(),
+		c.Offset,
+		c.Unit,
 	)
 }
+
+// schema returns the schema of the metric with the additional label names, key is the option key of the metric.
+func (c metricConfig) schema(key string, labelNames ...string) metricSchema {
+	labels := append(slices.Collect(maps.Keys(c.Labels)), labelNames...)
+	slices.Sort(labels)
+	return metricSchema{
+		Key:     key,
+		Name:    c.Name,
+		Help:    c.Help,
+		Type:    c.metricType(),
+		Unit:    c.Unit,
+		Labels:  labels,
+		Buckets: c.buckets(),
+	}
+}
+
+// metricSchema describes a metric created by an exporter.
+// All exporters creating a metric with the same name must use the same help, type and label names, as they share the metric.
+type metricSchema struct {
+	Key     string
+	Name    string
+	Help    string
+	Type    metricType
+	Unit    string
+	Labels  []string
+	Buckets []float64
+}
+
+func labelsKey(labels prometheus.Labels) string {
+	keys := slices.Sorted(maps.Keys(labels))
+
+	var sb strings.Builder
+	for _, key := range keys {
+		sb.WriteString(key)
+		sb.WriteByte('=')
+		sb.WriteString(labels[key])
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+type StaleMode string
+
+const (
+	StaleModeDelete StaleMode = "delete"
+	StaleModeNaN    StaleMode = "nan"
+)
+
+func (m StaleMode) Validate() error {
+	switch m {
+	case StaleModeDelete, StaleModeNaN:
+		return nil
+	default:
+		return fmt.Errorf("invalid stale mode %q, must be %s or %s", m, StaleModeDelete, StaleModeNaN)
+	}
+}
@@ -0,0 +1,111 @@
+package exporters
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRegistryMetricConflict(t *testing.T) {
+	base := metricConfig{
+		Name:    "test_seconds",
+		Help:    "Test",
+		Type:    metricTypeHistogram,
+		Buckets: []float64{1, 2, 5},
+		Unit:    "seconds",
+	}
+
+	tests := []struct {
+		name   string
+		config func(c metricConfig) metricConfig
+		labels []string
+		// release removes the first metric before the second one is created.
+		release bool
+		err     error
+	}{
+		{name: "same", config: func(c metricConfig) metricConfig { return c }},
+		{name: "other type", config: func(c metricConfig) metricConfig { c.Type = metricTypeGauge; c.Buckets = nil; return c }, err: ErrMetricConflict},
+		{name: "other labels", config: func(c metricConfig) metricConfig { return c }, labels: []string{"id"}, err: ErrMetricChanged},
+		{name: "other help", config: func(c metricConfig) metricConfig { c.Help = "Other"; return c }, err: ErrMetricChanged},
+		{name: "other unit", config: func(c metricConfig) metricConfig { c.Unit = ""; return c }, err: ErrMetricConflict},
+		{name: "other buckets", config: func(c metricConfig) metricConfig { c.Buckets = []float64{1, 2, 10}; return c }, err: ErrMetricConflict},
+		{name: "default buckets", config: func(c metricConfig) metricConfig { c.Buckets = nil; return c }, err: ErrMetricConflict},
+		{name: "other type after release", config: func(c metricConfig) metricConfig { c.Type = metricTypeGauge; c.Buckets = nil; return c }, release: true},
+		{name: "other buckets after release", config: func(c metricConfig) metricConfig { c.Buckets = []float64{1, 2, 10}; return c }, release: true},
+		{name: "other help after release", config: func(c metricConfig) metricConfig { c.Help = "Other"; return c }, release: true, err: ErrMetricChanged},
+		{name: "other labels after release", config: func(c metricConfig) metricConfig { return c }, labels: []string{"id"}, release: true, err: ErrMetricChanged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry(prometheus.NewRegistry())
+			if _, err := registry.metric(base, nil); err != nil {
+				t.Fatalf("metric() error = %v", err)
+			}
+			if tt.release {
+				registry.release(base.Name)
+			}
+
+			_, err := registry.metric(tt.config(base), tt.labels)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("metric() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("metric() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateMetricSchemasBuckets(t *testing.T) {
+	config := func(name string, buckets ...any) Config {
+		return Config{
+			Name: name,
+			Type: HTTPJSONType,
+			Options: map[string]any{
+				"url": "http://localhost",
+				"metrics": []any{
+					map[string]any{"name": "test_seconds", "help": "Test", "path": "$.value", "type": "histogram", "buckets": buckets},
+				},
+			},
+		}
+	}
+
+	if err := (Configs{config("a", 1.0, 2.0), config("b", 1.0, 2.0)}).validateMetricSchemas(); err != nil {
+		t.Errorf("validateMetricSchemas() with the same buckets error = %v", err)
+	}
+	if err := (Configs{config("a", 1.0, 2.0), config("b", 1.0, 5.0)}).validateMetricSchemas(); err == nil {
+		t.Error("validateMetricSchemas() with other buckets error = nil, want error")
+	}
+}
+
+func TestMarkStaleNaN(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	factory := NewRegistry(registry).Factory()
+
+	for _, c := range []metricConfig{
+		{Name: "test_gauge", Help: "Test", Type: metricTypeGauge},
+		{Name: "test_total", Help: "Test", Type: metricTypeCounter},
+		{Name: "test_info", Help: "Test", Type: metricTypeInfo},
+	} {
+		m, err := factory.metricFor(c)
+		if err != nil {
+			t.Fatalf("metricFor(%s) error = %v", c.Name, err)
+		}
+		if err = factory.observe(m, prometheus.Labels{}, 1); err != nil {
+			t.Fatalf("observe(%s) error = %v", c.Name, err)
+		}
+	}
+
+	factory.MarkStale(StaleModeNaN)
+
+	values := gatherValues(t, registry)
+	if len(values) != 1 || !math.IsNaN(values["test_gauge{}"]) {
+		t.Errorf("metrics = %v, want only test_gauge{} with NaN", values)
+	}
+}
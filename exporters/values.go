@@ -0,0 +1,47 @@
+package exporters
+
+import (
+	"context"
+	"errors"
+	"maps"
+)
+
+type valuesRecorderKey struct{}
+
+// ValuesRecorder is called with a copy of the values by name after each collection which set the metrics.
+type ValuesRecorder func(values map[string]float64)
+
+// WithValuesRecorder returns a context which makes the exporters pass the values of the collection to record.
+func WithValuesRecorder(ctx context.Context, record ValuesRecorder) context.Context {
+	return context.WithValue(ctx, valuesRecorderKey{}, record)
+}
+
+func newValuesExporter(exporter Exporter) Exporter {
+	source, ok := exporter.(valueSource)
+	if !ok {
+		return exporter
+	}
+	return &valuesExporter{
+		Exporter: exporter,
+		source:   source,
+	}
+}
+
+// valuesExporter passes the values to the ValuesRecorder of the context at the end of Collect,
+// so they are never read concurrently with a collection.
+type valuesExporter struct {
+	Exporter
+	source valueSource
+}
+
+func (e *valuesExporter) Collect(ctx context.Context) error {
+	err := e.Exporter.Collect(ctx)
+	var partialErr *PartialError
+	if err != nil && !errors.As(err, &partialErr) {
+		return err
+	}
+	if record, ok := ctx.Value(valuesRecorderKey{}).(ValuesRecorder); ok {
+		record(maps.Clone(e.source.values()))
+	}
+	return err
+}
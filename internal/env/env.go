@@ -0,0 +1,146 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Expand replaces ${NAME} in all string values reachable from v with the value of the environment variable NAME.
+// $${ is replaced with a literal ${, any other $ is kept as is. Errors contain the path of the value by the toml tags of the fields.
+func Expand(v any) error {
+	return expand(reflect.ValueOf(v), "")
+}
+
+func expand(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return expand(v.Elem(), path)
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		elem := v.Elem()
+		if elem.Kind() != reflect.String {
+			return expand(elem, path)
+		}
+		if !v.CanSet() {
+			return nil
+		}
+		expanded, err := expandString(elem.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.Set(reflect.ValueOf(expanded).Convert(elem.Type()))
+		return nil
+	case reflect.Struct:
+		var errs []error
+		t := v.Type()
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldPath := path
+			if !field.Anonymous {
+				fieldPath = joinPath(path, fieldName(field))
+			}
+			if err := expand(v.Field(i), fieldPath); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	case reflect.Slice, reflect.Array:
+		var errs []error
+		for i := range v.Len() {
+			if err := expand(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	case reflect.Map:
+		var errs []error
+		for _, key := range v.MapKeys() {
+			// map values aren't addressable, so expand a copy and set it back
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(key))
+			if err := expand(value, joinPath(path, fmt.Sprint(key.Interface()))); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			v.SetMapIndex(key, value)
+		}
+		return errors.Join(errs...)
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		expanded, err := expandString(v.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.SetString(expanded)
+		return nil
+	default:
+		return nil
+	}
+}
+
+func expandString(s string) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i == -1 {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+		sb.WriteString(s[:i])
+		s = s[i:]
+
+		switch {
+		case strings.HasPrefix(s, "$${"):
+			sb.WriteString("${")
+			s = s[3:]
+		case strings.HasPrefix(s, "${"):
+			end := strings.IndexByte(s, '}')
+			if end == -1 {
+				return "", errors.New("unterminated ${ in value")
+			}
+			name := s[2:end]
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				return "", fmt.Errorf("environment variable %s is not set", name)
+			}
+			sb.WriteString(value)
+			s = s[end+1:]
+		default:
+			sb.WriteByte('$')
+			s = s[1:]
+		}
+	}
+}
+
+// fieldName returns the name of the field in its toml tag.
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
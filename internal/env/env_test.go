@@ -0,0 +1,113 @@
+package env
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandString(t *testing.T) {
+	t.Setenv("HTTP_EXPORTER_TEST", "secret")
+	t.Setenv("HTTP_EXPORTER_EMPTY", "")
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "no variable", value: "plain", want: "plain"},
+		{name: "variable", value: "${HTTP_EXPORTER_TEST}", want: "secret"},
+		{name: "embedded variable", value: "a-${HTTP_EXPORTER_TEST}-b", want: "a-secret-b"},
+		{name: "multiple variables", value: "${HTTP_EXPORTER_TEST}${HTTP_EXPORTER_TEST}", want: "secretsecret"},
+		{name: "empty variable", value: "a${HTTP_EXPORTER_EMPTY}b", want: "ab"},
+		{name: "escaped variable", value: "$${HTTP_EXPORTER_TEST}", want: "${HTTP_EXPORTER_TEST}"},
+		{name: "double dollar is kept", value: "pa$$word", want: "pa$$word"},
+		{name: "single dollar is kept", value: "pa$word$", want: "pa$word$"},
+		{name: "dollar before variable", value: "$$$${HTTP_EXPORTER_TEST}", want: "$$${HTTP_EXPORTER_TEST}"},
+		{name: "unset variable", value: "${HTTP_EXPORTER_UNSET}", wantErr: true},
+		{name: "unterminated variable", value: "${HTTP_EXPORTER_TEST", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandString(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expandString(%q) = %q, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandString(%q) error = %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("expandString(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+type testOptions struct {
+	Password string `toml:"password"`
+}
+
+type testConfig struct {
+	Name    string            `toml:"name"`
+	Count   int               `toml:"count"`
+	Headers map[string]string `toml:"headers"`
+	Options map[string]any    `toml:"options"`
+	List    []string          `toml:"list"`
+	Nested  *testOptions      `toml:"nested"`
+}
+
+func TestExpand(t *testing.T) {
+	t.Setenv("HTTP_EXPORTER_TEST", "secret")
+
+	cfg := testConfig{
+		Name:    "${HTTP_EXPORTER_TEST}",
+		Count:   1,
+		Headers: map[string]string{"Authorization": "Bearer ${HTTP_EXPORTER_TEST}"},
+		Options: map[string]any{
+			"url":     "http://${HTTP_EXPORTER_TEST}",
+			"port":    int64(80),
+			"metrics": []any{map[string]any{"name": "${HTTP_EXPORTER_TEST}"}},
+		},
+		List:   []string{"$${HTTP_EXPORTER_TEST}"},
+		Nested: &testOptions{Password: "${HTTP_EXPORTER_TEST}"},
+	}
+	if err := Expand(&cfg); err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+
+	want := testConfig{
+		Name:    "secret",
+		Count:   1,
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+		Options: map[string]any{
+			"url":     "http://secret",
+			"port":    int64(80),
+			"metrics": []any{map[string]any{"name": "secret"}},
+		},
+		List:   []string{"${HTTP_EXPORTER_TEST}"},
+		Nested: &testOptions{Password: "secret"},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("Expand() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestExpandErrorPath(t *testing.T) {
+	cfg := testConfig{
+		Options: map[string]any{
+			"metrics": []any{map[string]any{"name": "${HTTP_EXPORTER_UNSET}"}},
+		},
+	}
+	err := Expand(&cfg)
+	if err == nil {
+		t.Fatal("Expand() error = nil, want error")
+	}
+	if !strings.HasPrefix(err.Error(), "options.metrics[0].name: ") {
+		t.Errorf("Expand() error = %q, want it to start with the path options.metrics[0].name", err)
+	}
+}
@@ -0,0 +1,353 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrUnknownVariable = errors.New("unknown variable")
+
+// Expr is a parsed arithmetic expression like dewpoint(temperature0, humidity) or a * 1.8 + 32.
+// It supports numbers, variables, + - * / ^, parentheses and the functions of the built-in library.
+type Expr struct {
+	raw  string
+	root node
+}
+
+func Parse(raw string) (Expr, error) {
+	p := &parser{raw: raw, s: raw}
+	root, err := p.parseExpr()
+	if err != nil {
+		return Expr{}, fmt.Errorf("invalid expression %q: %w", raw, err)
+	}
+	p.skipSpace()
+	if p.s != "" {
+		return Expr{}, fmt.Errorf("invalid expression %q: unexpected %q", raw, p.s)
+	}
+	return Expr{
+		raw:  raw,
+		root: root,
+	}, nil
+}
+
+func (e *Expr) UnmarshalText(text []byte) error {
+	expr, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*e = expr
+	return nil
+}
+
+func (e Expr) MarshalText() ([]byte, error) {
+	return []byte(e.raw), nil
+}
+
+func (e Expr) String() string {
+	return e.raw
+}
+
+// Vars returns the sorted names of the variables used in the expression.
+func (e Expr) Vars() []string {
+	var vars []string
+	if e.root != nil {
+		e.root.vars(&vars)
+	}
+	slices.Sort(vars)
+	return slices.Compact(vars)
+}
+
+// Eval evaluates the expression with the given variable values.
+// Errors wrap ErrUnknownVariable if a variable is missing.
+func (e Expr) Eval(vars map[string]float64) (float64, error) {
+	if e.root == nil {
+		return 0, errors.New("empty expression")
+	}
+	return e.root.eval(vars)
+}
+
+type node interface {
+	eval(vars map[string]float64) (float64, error)
+	vars(vars *[]string)
+}
+
+type number float64
+
+func (n number) eval(map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+func (n number) vars(*[]string) {}
+
+type variable string
+
+func (v variable) eval(vars map[string]float64) (float64, error) {
+	value, ok := vars[string(v)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownVariable, string(v))
+	}
+	return value, nil
+}
+
+func (v variable) vars(vars *[]string) {
+	*vars = append(*vars, string(v))
+}
+
+type negate struct {
+	x node
+}
+
+func (n negate) eval(vars map[string]float64) (float64, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	return -x, nil
+}
+
+func (n negate) vars(vars *[]string) {
+	n.x.vars(vars)
+}
+
+type binary struct {
+	op   byte
+	l, r node
+}
+
+func (b binary) eval(vars map[string]float64) (float64, error) {
+	l, err := b.l.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	r, err := b.r.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	switch b.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	case '/':
+		if r == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return l / r, nil
+	default:
+		return math.Pow(l, r), nil
+	}
+}
+
+func (b binary) vars(vars *[]string) {
+	b.l.vars(vars)
+	b.r.vars(vars)
+}
+
+type call struct {
+	fn   function
+	args []node
+}
+
+func (c call) eval(vars map[string]float64) (float64, error) {
+	args := make([]float64, len(c.args))
+	for i, arg := range c.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	return c.fn.fn(args...), nil
+}
+
+func (c call) vars(vars *[]string) {
+	for _, arg := range c.args {
+		arg.vars(vars)
+	}
+}
+
+// parser is a recursive descent parser with the grammar:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | power
+//	power   = primary [ "^" unary ]
+//	primary = number | name | name "(" [ expr { "," expr } ] ")" | "(" expr ")"
+type parser struct {
+	raw string
+	s   string
+}
+
+func (p *parser) skipSpace() {
+	p.s = strings.TrimLeft(p.s, " \t\r\n")
+}
+
+// consume skips spaces and consumes c if it is the next character.
+func (p *parser) consume(c byte) bool {
+	p.skipSpace()
+	if len(p.s) > 0 && p.s[0] == c {
+		p.s = p.s[1:]
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseExpr() (node, error) {
+	l, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		switch {
+		case p.consume('+'):
+			op = '+'
+		case p.consume('-'):
+			op = '-'
+		default:
+			return l, nil
+		}
+		r, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		l = binary{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) parseTerm() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		switch {
+		case p.consume('*'):
+			op = '*'
+		case p.consume('/'):
+			op = '/'
+		default:
+			return l, nil
+		}
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = binary{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.consume('-') {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negate{x: x}, nil
+	}
+	return p.parsePower()
+}
+
+func (p *parser) parsePower() (node, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.consume('^') {
+		return base, nil
+	}
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return binary{op: '^', l: base, r: exponent}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	p.skipSpace()
+	if p.s == "" {
+		return nil, errors.New("unexpected end of expression")
+	}
+
+	switch c := p.s[0]; {
+	case c == '(':
+		p.s = p.s[1:]
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(')') {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		return x, nil
+	case c >= '0' && c <= '9' || c == '.':
+		end := 0
+		for end < len(p.s) && (isDigit(p.s[end]) || p.s[end] == '.' ||
+			(p.s[end] == 'e' || p.s[end] == 'E') ||
+			((p.s[end] == '+' || p.s[end] == '-') && end > 0 && (p.s[end-1] == 'e' || p.s[end-1] == 'E'))) {
+			end++
+		}
+		f, err := strconv.ParseFloat(p.s[:end], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", p.s[:end])
+		}
+		p.s = p.s[end:]
+		return number(f), nil
+	case isNameStart(c):
+		end := 1
+		for end < len(p.s) && (isNameStart(p.s[end]) || isDigit(p.s[end])) {
+			end++
+		}
+		name := p.s[:end]
+		p.s = p.s[end:]
+		if !p.consume('(') {
+			return variable(name), nil
+		}
+		return p.parseCall(name)
+	default:
+		return nil, fmt.Errorf("unexpected %q", c)
+	}
+}
+
+func (p *parser) parseCall(name string) (node, error) {
+	fn, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+
+	var args []node
+	if !p.consume(')') {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.consume(')') {
+				break
+			}
+			if !p.consume(',') {
+				return nil, fmt.Errorf("missing closing parenthesis of %s", name)
+			}
+		}
+	}
+	if len(args) != fn.args {
+		return nil, fmt.Errorf("function %s expects %d arguments, got %d", name, fn.args, len(args))
+	}
+	return call{fn: fn, args: args}, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
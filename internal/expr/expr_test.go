@@ -0,0 +1,137 @@
+package expr
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	vars := map[string]float64{
+		"a":            2,
+		"b":            3,
+		"temperature0": 20,
+		"humidity":     50,
+		"zero":         0,
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want float64
+	}{
+		{name: "number", expr: "1.5", want: 1.5},
+		{name: "exponent number", expr: "1e3", want: 1000},
+		{name: "negative exponent number", expr: "2.5e-1", want: 0.25},
+		{name: "variable", expr: "a", want: 2},
+		{name: "multiplication before addition", expr: "1 + 2 * 3", want: 7},
+		{name: "division before subtraction", expr: "10 - 6 / 2", want: 7},
+		{name: "left associative subtraction", expr: "10 - 4 - 3", want: 3},
+		{name: "left associative division", expr: "64 / 4 / 2", want: 8},
+		{name: "power before multiplication", expr: "2 * 3 ^ 2", want: 18},
+		{name: "right associative power", expr: "2 ^ 3 ^ 2", want: 512},
+		{name: "unary minus after power", expr: "-2 ^ 2", want: -4},
+		{name: "negative exponent", expr: "2 ^ -1", want: 0.5},
+		{name: "double negation", expr: "--a", want: 2},
+		{name: "parentheses", expr: "(1 + 2) * 3", want: 9},
+		{name: "fahrenheit", expr: "temperature0 * 1.8 + 32", want: 68},
+		{name: "function", expr: "max(a, b) - min(a, b)", want: 1},
+		{name: "nested function", expr: "abs(round(-a * 1.4))", want: 3},
+		{name: "dewpoint", expr: "round(dewpoint(temperature0, humidity) * 10) / 10", want: 9.3},
+		{name: "spaces", expr: " a\t*\nb ", want: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+			}
+			got, err := e.Eval(vars)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr error
+	}{
+		{name: "division by zero", expr: "a / zero"},
+		{name: "division by zero expression", expr: "a / (b - 3)"},
+		{name: "unknown variable", expr: "a + c", wantErr: ErrUnknownVariable},
+		{name: "unknown variable in function", expr: "abs(c)", wantErr: ErrUnknownVariable},
+	}
+
+	vars := map[string]float64{"a": 2, "b": 3, "zero": 0}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+			}
+			got, err := e.Eval(vars)
+			if err == nil {
+				t.Fatalf("Eval() = %v, want error", got)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Eval() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"1 +",
+		"(1 + 2",
+		"1 2",
+		"a b",
+		"unknown(1)",
+		"max(1)",
+		"abs(1, 2)",
+		"max(1 2)",
+		"1..2",
+		"a # b",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := Parse(expr); err == nil {
+				t.Errorf("Parse(%q) error = nil, want error", expr)
+			}
+		})
+	}
+}
+
+func TestVars(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{expr: "1 + 2", want: nil},
+		{expr: "b * a + a", want: []string{"a", "b"}},
+		{expr: "dewpoint(temperature0, humidity) - -temperature0", want: []string{"humidity", "temperature0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+			}
+			if got := e.Vars(); !slices.Equal(got, tt.want) {
+				t.Errorf("Vars() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package expr
+
+import (
+	"math"
+)
+
+type function struct {
+	args int
+	fn   func(args ...float64) float64
+}
+
+// functions is the built-in function library. Temperatures are in °C and relative humidity in %.
+var functions = map[string]function{
+	"abs":   {args: 1, fn: func(args ...float64) float64 { return math.Abs(args[0]) }},
+	"min":   {args: 2, fn: func(args ...float64) float64 { return math.Min(args[0], args[1]) }},
+	"max":   {args: 2, fn: func(args ...float64) float64 { return math.Max(args[0], args[1]) }},
+	"round": {args: 1, fn: func(args ...float64) float64 { return math.Round(args[0]) }},
+	"sqrt":  {args: 1, fn: func(args ...float64) float64 { return math.Sqrt(args[0]) }},
+	"ln":    {args: 1, fn: func(args ...float64) float64 { return math.Log(args[0]) }},
+	"exp":   {args: 1, fn: func(args ...float64) float64 { return math.Exp(args[0]) }},
+	// dewpoint(temperature, humidity) is the dew point in °C.
+	"dewpoint": {args: 2, fn: func(args ...float64) float64 { return dewpoint(args[0], args[1]) }},
+	// heat_index(temperature, humidity) is the apparent temperature in °C.
+	"heat_index": {args: 2, fn: func(args ...float64) float64 { return heatIndex(args[0], args[1]) }},
+	// absolute_humidity(temperature, humidity) is the absolute humidity in g/m³.
+	"absolute_humidity": {args: 2, fn: func(args ...float64) float64 { return absoluteHumidity(args[0], args[1]) }},
+}
+
+// dewpoint uses the Magnus formula with the constants of Sonntag (1990).
+func dewpoint(t float64, rh float64) float64 {
+	const a, b = 17.62, 243.12
+	gamma := math.Log(rh/100) + a*t/(b+t)
+	return b * gamma / (a - gamma)
+}
+
+// heatIndex uses the regression of Rothfusz as used by the US National Weather Service.
+func heatIndex(t float64, rh float64) float64 {
+	f := t*9/5 + 32
+
+	hi := 0.5 * (f + 61 + (f-68)*1.2 + rh*0.094)
+	if (hi+f)/2 >= 80 {
+		hi = -42.379 + 2.04901523*f + 10.14333127*rh -
+			0.22475541*f*rh - 0.00683783*f*f - 0.05481717*rh*rh +
+			0.00122874*f*f*rh + 0.00085282*f*rh*rh - 0.00000199*f*f*rh*rh
+		switch {
+		case rh < 13 && f >= 80 && f <= 112:
+			hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(f-95))/17)
+		case rh > 85 && f >= 80 && f <= 87:
+			hi += (rh - 85) / 10 * (87 - f) / 5
+		}
+	}
+
+	return (hi - 32) * 5 / 9
+}
+
+func absoluteHumidity(t float64, rh float64) float64 {
+	return 6.112 * math.Exp(17.67*t/(t+243.5)) * rh * 2.1674 / (273.15 + t)
+}
@@ -0,0 +1,159 @@
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotFound = errors.New("path not found")
+	// ErrWildcard is returned by Parse for wildcards, arrays are expanded with the items option of the exporter instead.
+	ErrWildcard = errors.New("wildcards are not supported")
+)
+
+type segment struct {
+	key   string
+	index int
+	isKey bool
+}
+
+func (s segment) String() string {
+	if s.isKey {
+		return "." + s.key
+	}
+	return "[" + strconv.Itoa(s.index) + "]"
+}
+
+// Path is a parsed JSONPath-style expression like $.sensors[0].temp.
+// Only child access by name (.name or ['name']) and by array index ([0]) is supported.
+type Path struct {
+	raw      string
+	segments []segment
+}
+
+func Parse(raw string) (Path, error) {
+	s := strings.TrimSpace(raw)
+	s = strings.TrimPrefix(s, "$")
+
+	var segments []segment
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end == -1 {
+				end = len(s)
+			}
+			if end == 0 {
+				return Path{}, fmt.Errorf("invalid path %q: empty field name", raw)
+			}
+			if s[:end] == "*" {
+				return Path{}, fmt.Errorf("invalid path %q: %w", raw, ErrWildcard)
+			}
+			segments = append(segments, segment{key: s[:end], isKey: true})
+			s = s[end:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end == -1 {
+				return Path{}, fmt.Errorf("invalid path %q: missing closing bracket", raw)
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			if inner == "*" {
+				return Path{}, fmt.Errorf("invalid path %q: %w", raw, ErrWildcard)
+			}
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				segments = append(segments, segment{key: inner[1 : len(inner)-1], isKey: true})
+				continue
+			}
+			index, err := strconv.Atoi(inner)
+			if err != nil {
+				return Path{}, fmt.Errorf("invalid path %q: invalid index %q", raw, inner)
+			}
+			segments = append(segments, segment{index: index})
+		default:
+			return Path{}, fmt.Errorf("invalid path %q: unexpected character %q", raw, s[0])
+		}
+	}
+
+	return Path{
+		raw:      raw,
+		segments: segments,
+	}, nil
+}
+
+func (p *Path) UnmarshalText(text []byte) error {
+	path, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*p = path
+	return nil
+}
+
+func (p Path) String() string {
+	return p.raw
+}
+
+// Get walks the decoded JSON value v (as produced by encoding/json into an any) and returns the value at the path.
+func (p Path) Get(v any) (any, error) {
+	current := v
+	for i, seg := range p.segments {
+		if seg.isKey {
+			obj, ok := current.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: expected object at %s, got %s", p.raw, p.prefix(i), typeName(current))
+			}
+			next, ok := obj[seg.key]
+			if !ok {
+				return nil, fmt.Errorf("%s: %w: %s", p.raw, ErrNotFound, p.prefix(i+1))
+			}
+			current = next
+			continue
+		}
+
+		arr, ok := current.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected array at %s, got %s", p.raw, p.prefix(i), typeName(current))
+		}
+		index := seg.index
+		if index < 0 {
+			index += len(arr)
+		}
+		if index < 0 || index >= len(arr) {
+			return nil, fmt.Errorf("%s: %w: %s (array length %d)", p.raw, ErrNotFound, p.prefix(i+1), len(arr))
+		}
+		current = arr[index]
+	}
+	return current, nil
+}
+
+func (p Path) prefix(n int) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, seg := range p.segments[:n] {
+		sb.WriteString(seg.String())
+	}
+	return sb.String()
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
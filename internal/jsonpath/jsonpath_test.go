@@ -0,0 +1,121 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []segment
+		wantErr error
+	}{
+		{name: "root", raw: "$", want: nil},
+		{name: "empty", raw: "", want: nil},
+		{name: "field", raw: "$.temp", want: []segment{{key: "temp", isKey: true}}},
+		{name: "without root", raw: ".temp", want: []segment{{key: "temp", isKey: true}}},
+		{name: "nested", raw: "$.a.b", want: []segment{{key: "a", isKey: true}, {key: "b", isKey: true}}},
+		{name: "index", raw: "$.sensors[1].temp", want: []segment{{key: "sensors", isKey: true}, {index: 1}, {key: "temp", isKey: true}}},
+		{name: "negative index", raw: "$[-1]", want: []segment{{index: -1}}},
+		{name: "quoted key", raw: "$['a.b']", want: []segment{{key: "a.b", isKey: true}}},
+		{name: "double quoted key", raw: `$["a b"]`, want: []segment{{key: "a b", isKey: true}}},
+		{name: "surrounding space", raw: " $.a ", want: []segment{{key: "a", isKey: true}}},
+		{name: "field wildcard", raw: "$.sensors.*", wantErr: ErrWildcard},
+		{name: "index wildcard", raw: "$.sensors[*].temp", wantErr: ErrWildcard},
+		{name: "empty field", raw: "$..a", wantErr: errAny},
+		{name: "missing bracket", raw: "$[0", wantErr: errAny},
+		{name: "invalid index", raw: "$[a]", wantErr: errAny},
+		{name: "unexpected character", raw: "$a", wantErr: errAny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := Parse(tt.raw)
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatalf("Parse(%q) error = nil, want error", tt.raw)
+				}
+				if tt.wantErr != errAny && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want %v", tt.raw, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.raw, err)
+			}
+			if !reflect.DeepEqual(path.segments, tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.raw, path.segments, tt.want)
+			}
+			if path.String() != tt.raw {
+				t.Errorf("String() = %q, want %q", path.String(), tt.raw)
+			}
+		})
+	}
+}
+
+// errAny matches any error in the test tables.
+var errAny = errors.New("any error")
+
+func TestPathGet(t *testing.T) {
+	var data any
+	if err := json.Unmarshal([]byte(`{
+		"temp": 21.5,
+		"ok": true,
+		"name": null,
+		"sensors": [{"id": "a", "temp": 20}, {"id": "b", "temp": 22}],
+		"a.b": "dotted"
+	}`), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    any
+		wantErr error
+	}{
+		{name: "root", path: "$", want: data},
+		{name: "number", path: "$.temp", want: 21.5},
+		{name: "boolean", path: "$.ok", want: true},
+		{name: "null", path: "$.name", want: nil},
+		{name: "index", path: "$.sensors[1].id", want: "b"},
+		{name: "negative index", path: "$.sensors[-1].temp", want: float64(22)},
+		{name: "quoted key", path: "$['a.b']", want: "dotted"},
+		{name: "missing key", path: "$.humidity", wantErr: ErrNotFound},
+		{name: "missing nested key", path: "$.sensors[0].humidity", wantErr: ErrNotFound},
+		{name: "index out of range", path: "$.sensors[2]", wantErr: ErrNotFound},
+		{name: "negative index out of range", path: "$.sensors[-3]", wantErr: ErrNotFound},
+		{name: "index on object", path: "$[0]", wantErr: errAny},
+		{name: "key on array", path: "$.sensors.id", wantErr: errAny},
+		{name: "key on number", path: "$.temp.value", wantErr: errAny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := Parse(tt.path)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.path, err)
+			}
+			got, err := path.Get(data)
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatalf("Get() = %v, want error", got)
+				}
+				if tt.wantErr != errAny && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Get() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package secret
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	mask = "********"
+	// redacted replaces the value when marshaled.
+	redacted = "<redacted>"
+)
+
+var (
+	_ fmt.Stringer           = Secret("")
+	_ fmt.Formatter          = Secret("")
+	_ slog.LogValuer         = Secret("")
+	_ json.Marshaler         = Secret("")
+	_ encoding.TextMarshaler = Secret("")
+)
+
+// Secret is a string which masks itself when formatted, logged or marshaled.
+// Use Value to get the actual value.
+type Secret string
+
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return mask
+}
+
+func (s Secret) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'q':
+		_, _ = fmt.Fprintf(f, "%q", s.String())
+	default:
+		_, _ = f.Write([]byte(s.String()))
+	}
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// MarshalJSON marshals the secret as "<redacted>", so it isn't leaked when a config is encoded.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + s.redacted() + `"`), nil
+}
+
+// MarshalText marshals the secret as <redacted>, e.g. as map key or by encoders which use encoding.TextMarshaler.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.redacted()), nil
+}
+
+func (s Secret) redacted() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
@@ -0,0 +1,46 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret Secret
+		format string
+		json   string
+	}{
+		{name: "set", secret: "hunter2", format: "********", json: `{"password":"<redacted>","by_password":{"<redacted>":1}}`},
+		{name: "empty", secret: "", format: "", json: `{"password":"","by_password":{"":1}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprintf("%s", tt.secret); got != tt.format {
+				t.Errorf("Sprintf() = %q, want %q", got, tt.format)
+			}
+
+			var buf bytes.Buffer
+			encoder := json.NewEncoder(&buf)
+			encoder.SetEscapeHTML(false)
+			err := encoder.Encode(struct {
+				Password   Secret         `json:"password"`
+				ByPassword map[Secret]int `json:"by_password"`
+			}{
+				Password:   tt.secret,
+				ByPassword: map[Secret]int{tt.secret: 1},
+			})
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if got := strings.TrimSpace(buf.String()); got != tt.json {
+				t.Errorf("Encode() = %s, want %s", got, tt.json)
+			}
+		})
+	}
+}
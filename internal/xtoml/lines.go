@@ -0,0 +1,144 @@
+package xtoml
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// KeyLines parses the TOML document and returns the line of every key by its path, e.g. configs[0].options.address.
+func KeyLines(data []byte) (map[string]int, error) {
+	p := &unstable.Parser{}
+	p.Reset(data)
+
+	lines := map[string]int{}
+	arrayTables := map[string]int{}
+
+	resolve := func(parts []string) string {
+		var path string
+		for _, part := range parts {
+			path = joinPath(path, part)
+			if n, ok := arrayTables[path]; ok {
+				path = fmt.Sprintf("%s[%d]", path, n-1)
+			}
+		}
+		return path
+	}
+
+	var prefix string
+	for p.NextExpression() {
+		expr := p.Expression()
+		parts, line := keyParts(p, expr)
+		switch expr.Kind {
+		case unstable.Table:
+			prefix = resolve(parts)
+			lines[prefix] = line
+		case unstable.ArrayTable:
+			base := joinPath(resolve(parts[:len(parts)-1]), parts[len(parts)-1])
+			index := arrayTables[base]
+			arrayTables[base]++
+			prefix = fmt.Sprintf("%s[%d]", base, index)
+			lines[prefix] = line
+		case unstable.KeyValue:
+			path := joinPath(prefix, strings.Join(parts, "."))
+			lines[path] = line
+			valueLines(p, expr.Value(), path, line, lines)
+		}
+	}
+	if err := p.Error(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+func valueLines(p *unstable.Parser, value *unstable.Node, path string, line int, lines map[string]int) {
+	switch value.Kind {
+	case unstable.InlineTable:
+		it := value.Children()
+		for it.Next() {
+			kv := it.Node()
+			parts, kvLine := keyParts(p, kv)
+			if kvLine == 0 {
+				kvLine = line
+			}
+			kvPath := joinPath(path, strings.Join(parts, "."))
+			lines[kvPath] = kvLine
+			valueLines(p, kv.Value(), kvPath, kvLine, lines)
+		}
+	case unstable.Array:
+		it := value.Children()
+		for i := 0; it.Next(); i++ {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			lines[elemPath] = line
+			valueLines(p, it.Node(), elemPath, line, lines)
+		}
+	}
+}
+
+func keyParts(p *unstable.Parser, node *unstable.Node) ([]string, int) {
+	var (
+		parts []string
+		line  int
+	)
+	it := node.Key()
+	for it.Next() {
+		key := it.Node()
+		if line == 0 && key.Raw.Length > 0 {
+			line = p.Shape(key.Raw).Start.Line
+		}
+		parts = append(parts, string(key.Data))
+	}
+	return parts, line
+}
+
+// Line returns the line of the key in lines, 0 if it is not found.
+// Except for the first element, keys inside arrays may lack their index as go-toml reports them without,
+// in this case the first line of a matching key is returned.
+func Line(lines map[string]int, key []string) int {
+	if len(key) == 0 {
+		return 0
+	}
+	path := strings.Join(key, ".")
+	if line, ok := lines[path]; ok {
+		return line
+	}
+
+	prefix := key[0] + "."
+	path = stripIndices(path[len(prefix):])
+	var first int
+	for k, line := range lines {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if stripIndices(k[len(prefix):]) == path && (first == 0 || line < first) {
+			first = line
+		}
+	}
+	return first
+}
+
+func stripIndices(path string) string {
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(path, '[')
+		if start == -1 {
+			sb.WriteString(path)
+			return sb.String()
+		}
+		sb.WriteString(path[:start])
+		end := strings.IndexByte(path[start:], ']')
+		if end == -1 {
+			sb.WriteString(path[start:])
+			return sb.String()
+		}
+		path = path[start+end+1:]
+	}
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
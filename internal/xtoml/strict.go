@@ -0,0 +1,172 @@
+package xtoml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// UnknownKey is a key in a TOML document which does not match any field of the destination struct.
+type UnknownKey struct {
+	Key []string
+	// Line is the line of the key in the document, 0 if unknown.
+	Line int
+	// Suggestion is the closest valid key, empty if there is none.
+	Suggestion string
+}
+
+func (k UnknownKey) String() string {
+	var sb strings.Builder
+	if k.Line > 0 {
+		fmt.Fprintf(&sb, "line %d: ", k.Line)
+	}
+	fmt.Fprintf(&sb, "unknown key %s", strings.Join(k.Key, "."))
+	if k.Suggestion != "" {
+		fmt.Fprintf(&sb, ", did you mean %s?", k.Suggestion)
+	}
+	return sb.String()
+}
+
+type UnknownKeysError struct {
+	Keys []UnknownKey
+}
+
+func (e *UnknownKeysError) Error() string {
+	keys := make([]string, len(e.Keys))
+	for i, key := range e.Keys {
+		keys[i] = key.String()
+	}
+	return strings.Join(keys, "\n")
+}
+
+// Decode strictly decodes the TOML document into v and returns an *UnknownKeysError if the document contains unknown keys.
+func Decode(data []byte, v any) error {
+	err := toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields().Decode(v)
+
+	var strictErr *toml.StrictMissingError
+	if !errors.As(err, &strictErr) {
+		return err
+	}
+
+	t := reflect.TypeOf(v)
+	keys := make([]UnknownKey, len(strictErr.Errors))
+	for i, decodeErr := range strictErr.Errors {
+		line, _ := decodeErr.Position()
+		key := []string(decodeErr.Key())
+		keys[i] = UnknownKey{
+			Key:        key,
+			Line:       line,
+			Suggestion: suggestKey(t, key),
+		}
+	}
+	return &UnknownKeysError{Keys: keys}
+}
+
+// suggestKey returns the valid key closest to the last element of key in the struct found at key[:len(key)-1] in t.
+func suggestKey(t reflect.Type, key []string) string {
+	if len(key) == 0 {
+		return ""
+	}
+	parent := structAt(t, key[:len(key)-1])
+	if parent == nil {
+		return ""
+	}
+
+	unknown := key[len(key)-1]
+	var (
+		best         string
+		bestDistance = len(unknown)/3 + 2
+	)
+	for _, name := range fieldNames(parent) {
+		if distance := levenshtein(unknown, name); distance < bestDistance {
+			best = name
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+func structAt(t reflect.Type, key []string) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+		}
+		break
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if len(key) == 0 {
+		return t
+	}
+	field, ok := fieldByName(t, key[0])
+	if !ok {
+		return nil
+	}
+	return structAt(field.Type, key[1:])
+}
+
+func fieldByName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if f, ok := fieldByName(field.Type, name); ok {
+				return f, true
+			}
+			continue
+		}
+		if field.IsExported() && tomlName(field) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func fieldNames(t reflect.Type) []string {
+	var names []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			names = append(names, fieldNames(field.Type)...)
+			continue
+		}
+		if field.IsExported() && field.Tag.Get("toml") != "-" {
+			names = append(names, tomlName(field))
+		}
+	}
+	return names
+}
+
+func levenshtein(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func tomlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
@@ -1,18 +1,27 @@
 package xtoml
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/pelletier/go-toml/v2"
 )
 
+// UnmarshalMap strictly unmarshals the map into v, unknown keys are reported as *UnknownKeysError.
 func UnmarshalMap(data map[string]any, v any) error {
 	rawData, err := toml.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("marshal config data: %w", err)
 	}
 
-	if err = toml.Unmarshal(rawData, v); err != nil {
+	if err = Decode(rawData, v); err != nil {
+		var unknownErr *UnknownKeysError
+		if errors.As(err, &unknownErr) {
+			// the lines refer to the marshalled map and are meaningless to the user
+			for i := range unknownErr.Keys {
+				unknownErr.Keys[i].Line = 0
+			}
+		}
 		return fmt.Errorf("unmarshal config data: %w", err)
 	}
 
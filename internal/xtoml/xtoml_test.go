@@ -0,0 +1,201 @@
+package xtoml
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testOptions struct {
+	Address string `toml:"address"`
+	Timeout string `toml:"timeout"`
+}
+
+type testEntry struct {
+	Name    string      `toml:"name"`
+	Options testOptions `toml:"options"`
+}
+
+type testConfig struct {
+	LogLevel string      `toml:"log_level"`
+	Entries  []testEntry `toml:"entries"`
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []UnknownKey
+	}{
+		{
+			name: "valid",
+			data: `log_level = "info"
+
+[[entries]]
+name = "a"
+options.address = "localhost"
+`,
+		},
+		{
+			name: "unknown top level key",
+			data: `log_level = "info"
+log_levle = "debug"
+`,
+			want: []UnknownKey{{Key: []string{"log_levle"}, Line: 2, Suggestion: "log_level"}},
+		},
+		{
+			name: "unknown nested key",
+			data: `[[entries]]
+name = "a"
+
+[entries.options]
+address = "localhost"
+timeuot = "5s"
+`,
+			want: []UnknownKey{{Key: []string{"entries", "options", "timeuot"}, Line: 6, Suggestion: "timeout"}},
+		},
+		{
+			name: "no suggestion",
+			data: `[[entries]]
+name = "a"
+something = true
+`,
+			want: []UnknownKey{{Key: []string{"entries", "something"}, Line: 3}},
+		},
+		{
+			name: "multiple unknown keys",
+			data: `nmae = "a"
+
+[[entries]]
+nmae = "b"
+`,
+			want: []UnknownKey{
+				{Key: []string{"nmae"}, Line: 1},
+				{Key: []string{"entries", "nmae"}, Line: 4, Suggestion: "name"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg testConfig
+			err := Decode([]byte(tt.data), &cfg)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				return
+			}
+
+			var unknownErr *UnknownKeysError
+			if !errors.As(err, &unknownErr) {
+				t.Fatalf("Decode() error = %v, want *UnknownKeysError", err)
+			}
+			if !reflect.DeepEqual(unknownErr.Keys, tt.want) {
+				t.Errorf("Decode() keys = %+v, want %+v", unknownErr.Keys, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownKeyString(t *testing.T) {
+	tests := []struct {
+		key  UnknownKey
+		want string
+	}{
+		{key: UnknownKey{Key: []string{"a", "b"}}, want: "unknown key a.b"},
+		{key: UnknownKey{Key: []string{"a"}, Line: 3}, want: "line 3: unknown key a"},
+		{key: UnknownKey{Key: []string{"nmae"}, Line: 1, Suggestion: "name"}, want: "line 1: unknown key nmae, did you mean name?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.key.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalMap(t *testing.T) {
+	var opts testOptions
+	err := UnmarshalMap(map[string]any{"address": "localhost", "adress": "localhost"}, &opts)
+
+	var unknownErr *UnknownKeysError
+	if !errors.As(err, &unknownErr) {
+		t.Fatalf("UnmarshalMap() error = %v, want *UnknownKeysError", err)
+	}
+	want := []UnknownKey{{Key: []string{"adress"}, Suggestion: "address"}}
+	if !reflect.DeepEqual(unknownErr.Keys, want) {
+		t.Errorf("UnmarshalMap() keys = %+v, want %+v", unknownErr.Keys, want)
+	}
+}
+
+func TestKeyLines(t *testing.T) {
+	data := `log_level = "info"
+
+[[entries]]
+name = "a"
+options = { address = "localhost", timeout = "5s" }
+
+[[entries]]
+name = "b"
+
+[entries.options]
+address = "remote"
+tags = ["x", "y"]
+`
+	want := map[string]int{
+		"log_level":                  1,
+		"entries[0]":                 3,
+		"entries[0].name":            4,
+		"entries[0].options":         5,
+		"entries[0].options.address": 5,
+		"entries[0].options.timeout": 5,
+		"entries[1]":                 7,
+		"entries[1].name":            8,
+		"entries[1].options":         10,
+		"entries[1].options.address": 11,
+		"entries[1].options.tags":    12,
+		"entries[1].options.tags[0]": 12,
+		"entries[1].options.tags[1]": 12,
+	}
+
+	got, err := KeyLines([]byte(data))
+	if err != nil {
+		t.Fatalf("KeyLines() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("KeyLines() = %v, want %v", got, want)
+	}
+}
+
+func TestLine(t *testing.T) {
+	lines := map[string]int{
+		"log_level":                        1,
+		"entries[0].options.items[0].name": 5,
+		"entries[0].options.items[1].name": 7,
+		"entries[1].options.items[0].name": 12,
+	}
+
+	tests := []struct {
+		name string
+		key  []string
+		want int
+	}{
+		{name: "empty", key: nil, want: 0},
+		{name: "exact", key: []string{"log_level"}, want: 1},
+		{name: "exact with indices", key: []string{"entries[1]", "options", "items[0]", "name"}, want: 12},
+		{name: "missing inner indices", key: []string{"entries[0]", "options", "items", "name"}, want: 5},
+		{name: "first element index is kept", key: []string{"entries[1]", "options", "items", "name"}, want: 12},
+		{name: "not found", key: []string{"entries[0]", "options", "unknown"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Line(lines, tt.key); got != tt.want {
+				t.Errorf("Line(%v) = %d, want %d", tt.key, got, tt.want)
+			}
+		})
+	}
+}
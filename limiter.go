@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// scrapeLimiter limits the number of concurrent collections of all exporters.
+type scrapeLimiter struct {
+	sem atomic.Pointer[chan struct{}]
+}
+
+// SetLimit replaces the limit, 0 disables it.
+// Collections which are already running count against the previous limit until they finish.
+func (l *scrapeLimiter) SetLimit(limit int) {
+	if limit <= 0 {
+		l.sem.Store(nil)
+		return
+	}
+	if sem := l.sem.Load(); sem != nil && cap(*sem) == limit {
+		return
+	}
+	sem := make(chan struct{}, limit)
+	l.sem.Store(&sem)
+}
+
+// acquire waits until a collection may start and returns a func which must be called when it finished.
+// It returns the context error if ctx is done first.
+func (l *scrapeLimiter) acquire(ctx context.Context) (func(), error) {
+	sem := l.sem.Load()
+	if sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case *sem <- struct{}{}:
+		return func() { <-*sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
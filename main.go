@@ -8,8 +8,13 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/topi314/prometheus-collectors/exporters"
 )
 
 var (
@@ -18,7 +23,17 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "check-config":
+			os.Exit(runCheckConfig(os.Args[2:]))
+		case "scrape":
+			os.Exit(runScrape(os.Args[2:]))
+		}
+	}
+
 	cfgPath := flag.String("config", "config.toml", "Path to config file")
+	watch := flag.Bool("watch", false, "Reload the config file when it changes")
 	flag.Parse()
 
 	slog.Info("Starting HTTP Exporter...", slog.String("version", Version), slog.String("commit", Commit), slog.String("config", *cfgPath))
@@ -36,9 +51,17 @@ func main() {
 
 	setupLogger(cfg.Log)
 
+	probe := newProbeHandler(cfg)
+	registry := exporters.NewRegistry(prometheus.DefaultRegisterer)
+	status := newStatusStore()
+	onDemand := newOnDemandCollectors()
+
 	mux := http.NewServeMux()
-	mux.Handle(cfg.Server.Endpoint, promhttp.Handler())
+	mux.Handle(cfg.Server.Endpoint, promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, metricsHandler(onDemand.gatherer(prometheus.DefaultGatherer), registry)))
+	mux.Handle("/probe", probe)
 	mux.HandleFunc("/version", versionHandler(Version))
+	mux.HandleFunc("/status", statusHandler(status))
+	mux.HandleFunc("/api/v1/exporters", exportersHandler(status))
 	server := &http.Server{
 		Addr:    cfg.Server.ListenAddr,
 		Handler: mux,
@@ -48,21 +71,56 @@ func main() {
 			slog.Error("Failed to start server", slog.Any("err", err))
 		}
 	}()
-	defer func() {
-		if err = server.Shutdown(context.Background()); err != nil {
-			slog.Error("Failed to shutdown server", slog.Any("err", err))
-		}
-	}()
 
 	s := make(chan os.Signal, 1)
-	signal.Notify(s, os.Interrupt)
+	signal.Notify(s, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	go startExporters(ctx, cfg)
+
+	manager := newExporterManager(ctx, registry, status, onDemand)
+	// exporters which can't be created are reported in their status and retried on the next reload
+	if err = manager.Apply(cfg); err != nil {
+		slog.Error("Failed to start all exporters", slog.Any("err", err))
+	}
+	markConfigReloaded(err == nil)
+
+	var configChanges <-chan struct{}
+	if *watch {
+		configChanges = watchConfig(ctx, *cfgPath)
+	}
 
 	slog.Info("Started HTTP Exporter", slog.String("addr", cfg.Server.ListenAddr), slog.String("endpoint", cfg.Server.Endpoint))
-	<-s
+	for {
+		select {
+		case sig := <-s:
+			if sig != syscall.SIGHUP {
+				slog.Info("Shutting down HTTP Exporter", slog.String("signal", sig.String()))
+				shutdown(manager, server, time.Duration(cfg.Server.ShutdownTimeout))
+				slog.Info("Stopped HTTP Exporter")
+				return
+			}
+			cfg = reloadConfig(*cfgPath, cfg, manager, probe)
+		case <-configChanges:
+			cfg = reloadConfig(*cfgPath, cfg, manager, probe)
+		}
+	}
+}
+
+// shutdown stops scheduling collections, waits for the collections in flight and then shuts the server down.
+// Waiting for the collections and shutting the server down are each bounded by timeout.
+func shutdown(manager *exporterManager, server *http.Server, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if pending := manager.Shutdown(ctx); len(pending) > 0 {
+		slog.Error("Aborted the collections of exporters which did not finish in time", slog.Any("exporters", pending), slog.Duration("timeout", timeout))
+	}
+
+	serverCtx, serverCancel := context.WithTimeout(context.Background(), timeout)
+	defer serverCancel()
+	if err := server.Shutdown(serverCtx); err != nil {
+		slog.Error("Failed to shutdown server", slog.Any("err", err))
+	}
 }
 
 func setupLogger(cfg LogConfig) {
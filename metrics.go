@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/topi314/prometheus-collectors/exporters"
+)
+
+// unitGatherer sets the units of the exporter metrics on the metric families of the gatherer,
+// client_golang has no option to set the unit of a metric.
+type unitGatherer struct {
+	gatherer prometheus.Gatherer
+	registry *exporters.Registry
+}
+
+func (g unitGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	units := g.registry.Units()
+	for _, family := range families {
+		if unit, ok := units[family.GetName()]; ok {
+			family.Unit = &unit
+		}
+	}
+	return families, err
+}
+
+// metricsHandler serves the metrics of the gatherer including the units of the exporter metrics.
+func metricsHandler(gatherer prometheus.Gatherer, registry *exporters.Registry) http.Handler {
+	return promhttp.HandlerFor(unitGatherer{
+		gatherer: gatherer,
+		registry: registry,
+	}, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+}
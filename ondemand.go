@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/topi314/prometheus-collectors/exporters"
+)
+
+func newOnDemandCollectors() *onDemandCollectors {
+	return &onDemandCollectors{
+		collectors: map[*onDemandCollector]struct{}{},
+	}
+}
+
+// onDemandCollectors are the running on_demand exporters, which are collected before the metrics endpoint is gathered.
+type onDemandCollectors struct {
+	mu         sync.Mutex
+	collectors map[*onDemandCollector]struct{}
+}
+
+func (c *onDemandCollectors) add(collector *onDemandCollector) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.collectors[collector] = struct{}{}
+}
+
+func (c *onDemandCollectors) remove(collector *onDemandCollector) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.collectors, collector)
+}
+
+// refresh refreshes all on_demand exporters concurrently and waits for them.
+func (c *onDemandCollectors) refresh() {
+	c.mu.Lock()
+	collectors := make([]*onDemandCollector, 0, len(c.collectors))
+	for collector := range c.collectors {
+		collectors = append(collectors, collector)
+	}
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, collector := range collectors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			collector.refresh()
+		}()
+	}
+	wg.Wait()
+}
+
+// gatherer returns a prometheus.Gatherer which refreshes the on_demand exporters before gathering the metrics of gatherer.
+// The on_demand exporters share their metrics with the other exporters in the exporters.Registry, only their collection is triggered by the scrape.
+func (c *onDemandCollectors) gatherer(gatherer prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		c.refresh()
+		return gatherer.Gather()
+	})
+}
+
+func newOnDemandCollector(ctx context.Context, logger *slog.Logger, cfg exporters.Config) *onDemandCollector {
+	return &onDemandCollector{
+		ctx:    ctx,
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// onDemandCollector collects the data of an exporter during the scrape of the metrics endpoint.
+// Concurrent scrapes share a single collection and the collected data is reused for the configured cache_ttl.
+type onDemandCollector struct {
+	ctx    context.Context
+	logger *slog.Logger
+	cfg    exporters.Config
+
+	mu          sync.Mutex
+	exporter    exporters.Exporter
+	state       *scrapeState
+	lastCollect time.Time
+	inflight    chan struct{}
+}
+
+// run adds the collector to collectors until ctx is done and then waits for the collection in flight.
+func (c *onDemandCollector) run(ctx context.Context, collectors *onDemandCollectors, exporter exporters.Exporter, state *scrapeState) {
+	c.mu.Lock()
+	c.exporter = exporter
+	c.state = state
+	c.mu.Unlock()
+
+	collectors.add(c)
+	<-ctx.Done()
+	collectors.remove(c)
+
+	c.mu.Lock()
+	c.exporter = nil
+	inflight := c.inflight
+	c.mu.Unlock()
+	if inflight != nil {
+		<-inflight
+	}
+}
+
+// refresh collects the exporter data unless it is still fresh or another scrape is already collecting it.
+func (c *onDemandCollector) refresh() {
+	c.mu.Lock()
+	if c.exporter == nil || time.Since(c.lastCollect) < time.Duration(c.cfg.CacheTTL) {
+		c.mu.Unlock()
+		return
+	}
+	if inflight := c.inflight; inflight != nil {
+		c.mu.Unlock()
+		<-inflight
+		return
+	}
+	inflight := make(chan struct{})
+	c.inflight = inflight
+	exporter, state := c.exporter, c.state
+	c.mu.Unlock()
+
+	state.collect(c.ctx, c.logger, exporter, c.cfg)
+
+	c.mu.Lock()
+	c.lastCollect = time.Now()
+	c.inflight = nil
+	c.mu.Unlock()
+	close(inflight)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/topi314/prometheus-collectors/exporters"
+)
+
+func newProbeHandler(cfg Config) *probeHandler {
+	h := &probeHandler{}
+	h.Update(cfg)
+	return h
+}
+
+// probeHandler creates an exporter from a module for the requested target and serves only the metrics of this target.
+type probeHandler struct {
+	cfg atomic.Pointer[Config]
+}
+
+// Update replaces the config used for new probes.
+func (h *probeHandler) Update(cfg Config) {
+	h.cfg.Store(&cfg)
+}
+
+func (h *probeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cfg := h.cfg.Load()
+
+	moduleName := r.URL.Query().Get("module")
+	module, ok := cfg.Probe.Module(moduleName)
+	if !ok {
+		http.Error(w, "unknown module "+moduleName, http.StatusBadRequest)
+		return
+	}
+
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "target is required", http.StatusBadRequest)
+		return
+	}
+	if err := validateProbeTarget(target); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !cfg.Probe.TargetAllowed(target) {
+		http.Error(w, "target "+target+" is not allowed", http.StatusForbidden)
+		return
+	}
+
+	if module.Timeout == 0 {
+		module.Timeout = cfg.Global.ScrapeTimeout
+	}
+	module = withProbeTarget(module, target)
+
+	logger := slog.With(
+		slog.String("module", module.Name),
+		slog.String("type", module.Type),
+		slog.String("target", target),
+	)
+
+	registry := prometheus.NewRegistry()
+	probeSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_exporter_probe_success",
+		Help: "Whether the probe was successful.",
+	})
+	probeDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_exporter_probe_duration_seconds",
+		Help: "Duration of the probe in seconds.",
+	})
+	registry.MustRegister(probeSuccess, probeDuration)
+
+	metrics := exporters.NewRegistry(registry)
+	factory := metrics.Factory()
+	exporter, err := exporters.New(module, logger, factory)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "failed to create probe exporter", slog.Any("err", err))
+		if errors.Is(err, exporters.ErrExporterNotFound) {
+			http.Error(w, "exporter type not found", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, "failed to create exporter", http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		if closeErr := exporter.Close(); closeErr != nil {
+			logger.ErrorContext(r.Context(), "failed to close probe exporter", slog.Any("err", closeErr))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(module.Timeout))
+	defer cancel()
+
+	start := time.Now()
+	err = exporter.Collect(ctx)
+	probeDuration.Set(time.Since(start).Seconds())
+	var partialErr *exporters.PartialError
+	switch {
+	case err == nil:
+		probeSuccess.Set(1)
+	case errors.As(err, &partialErr):
+		logger.WarnContext(ctx, "failed to probe metrics of target", slog.Any("err", err))
+		probeSuccess.Set(1)
+	default:
+		logger.ErrorContext(ctx, "failed to probe target", slog.String("reason", errorReason(err)), slog.Any("err", err))
+	}
+
+	metricsHandler(registry, metrics).ServeHTTP(w, r)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const configWatchInterval = 5 * time.Second
+
+var (
+	configLastReloadSuccessful = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_exporter_config_last_reload_successful",
+		Help: "Whether the last configuration reload attempt was successful.",
+	})
+	configLastReloadSuccessTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_exporter_config_last_reload_success_timestamp_seconds",
+		Help: "Timestamp of the last successful configuration reload.",
+	})
+)
+
+func markConfigReloaded(success bool) {
+	if !success {
+		configLastReloadSuccessful.Set(0)
+		return
+	}
+	configLastReloadSuccessful.Set(1)
+	configLastReloadSuccessTimestamp.SetToCurrentTime()
+}
+
+// reloadConfig loads and validates the config at path and applies it to the manager and probe handler.
+// If the new config is invalid the running exporters are kept and the current config is returned.
+// If some exporters can't be started with the new config, the reload is marked as failed.
+func reloadConfig(path string, current Config, manager *exporterManager, probe *probeHandler) Config {
+	slog.Info("Reloading config", slog.String("config", path))
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		slog.Error("Failed to reload config, keeping current config", slog.Any("err", err))
+		markConfigReloaded(false)
+		return current
+	}
+	if err = cfg.Validate(); err != nil {
+		slog.Error("Invalid config, keeping current config", slog.Any("err", err))
+		markConfigReloaded(false)
+		return current
+	}
+
+	if cfg.Server != current.Server {
+		slog.Warn("Server config changed, restart required to apply", slog.String("server", cfg.Server.String()))
+		cfg.Server = current.Server
+	}
+
+	setupLogger(cfg.Log)
+	probe.Update(cfg)
+	// the exporters which failed keep running with their previous config or are retried on the next reload,
+	// the other exporters and the probe modules use the new config, so it is still returned
+	if err = manager.Apply(cfg); err != nil {
+		slog.Error("Failed to apply config to all exporters", slog.Any("err", err))
+	}
+	markConfigReloaded(err == nil)
+
+	slog.Info("Reloaded config", slog.String("config", cfg.String()))
+	return cfg
+}
+
+// watchConfig polls the modification time of the config file at path and sends on the returned channel when it changed.
+func watchConfig(ctx context.Context, path string) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+
+	modTime := func() time.Time {
+		info, err := os.Stat(path)
+		if err != nil {
+			slog.Error("Failed to stat config file", slog.Any("err", err))
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+
+	go func() {
+		ticker := time.NewTicker(configWatchInterval)
+		defer ticker.Stop()
+
+		lastModTime := modTime()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				current := modTime()
+				if current.IsZero() || current.Equal(lastModTime) {
+					continue
+				}
+				lastModTime = current
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes
+}
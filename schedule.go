@@ -0,0 +1,59 @@
+package main
+
+import (
+	"hash/fnv"
+	"time"
+
+	"github.com/topi314/prometheus-collectors/exporters"
+)
+
+func newSchedule(cfg exporters.Config) schedule {
+	interval := time.Duration(cfg.Interval)
+	start := time.Unix(0, 0)
+	if !cfg.Align {
+		start = start.Add(jitter(cfg.Name, interval))
+	}
+	return schedule{
+		interval: interval,
+		start:    start,
+	}
+}
+
+// schedule computes the collection times of an exporter in timer mode, which are every interval before and after start.
+// Aligned schedules start at the unix epoch, so they collect at multiples of the interval, e.g. at the full minute for 1m.
+// Other schedules start offset from the epoch by a jitter, which spreads the collections of the exporters over the interval.
+type schedule struct {
+	interval time.Duration
+	start    time.Time
+}
+
+// slot returns the index of the last collection time at or before t.
+func (s schedule) slot(t time.Time) int64 {
+	d := t.Sub(s.start)
+	n := int64(d / s.interval)
+	if d < 0 && d%s.interval != 0 {
+		n--
+	}
+	return n
+}
+
+// next returns the first collection time after now.
+func (s schedule) next(now time.Time) time.Time {
+	return s.start.Add(time.Duration(s.slot(now)+1) * s.interval)
+}
+
+// missed returns the number of collection times after at until now.
+func (s schedule) missed(at time.Time, now time.Time) int {
+	return int(s.slot(now) - s.slot(at))
+}
+
+// jitter returns a deterministic duration in [0, interval) derived from the exporter name.
+// As schedules start at the epoch, an exporter keeps its collection times across restarts.
+func jitter(name string, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return time.Duration(h.Sum64() % uint64(interval))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/topi314/prometheus-collectors/exporters"
+	"github.com/topi314/prometheus-collectors/internal/xtime"
+)
+
+func TestScheduleSlot(t *testing.T) {
+	start := time.Unix(1000, 0)
+	s := schedule{interval: 10 * time.Second, start: start}
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   int64
+	}{
+		{name: "start", offset: 0, want: 0},
+		{name: "within first interval", offset: 9 * time.Second, want: 0},
+		{name: "next slot", offset: 10 * time.Second, want: 1},
+		{name: "later slot", offset: 35 * time.Second, want: 3},
+		{name: "just before start", offset: -time.Nanosecond, want: -1},
+		{name: "one slot before start", offset: -10 * time.Second, want: -1},
+		{name: "negative within slot", offset: -15 * time.Second, want: -2},
+		{name: "two slots before start", offset: -20 * time.Second, want: -2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.slot(start.Add(tt.offset)); got != tt.want {
+				t.Errorf("slot(start%+v) = %d, want %d", tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	s := newSchedule(exporters.Config{Name: "test", Interval: xtime.Duration(time.Minute), Align: true})
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{name: "before epoch", now: time.Unix(-90, 0), want: time.Unix(-60, 0)},
+		{name: "just before epoch", now: time.Unix(0, -1), want: time.Unix(0, 0)},
+		{name: "at epoch", now: time.Unix(0, 0), want: time.Unix(60, 0)},
+		{name: "after epoch", now: time.Unix(30, 0), want: time.Unix(60, 0)},
+		{name: "at full minute", now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), want: time.Date(2024, 5, 1, 12, 1, 0, 0, time.UTC)},
+		{name: "within minute", now: time.Date(2024, 5, 1, 12, 0, 59, 999, time.UTC), want: time.Date(2024, 5, 1, 12, 1, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.next(tt.now); !got.Equal(tt.want) {
+				t.Errorf("next(%s) = %s, want %s", tt.now.UTC(), got.UTC(), tt.want.UTC())
+			}
+		})
+	}
+}
+
+func TestScheduleMissed(t *testing.T) {
+	s := newSchedule(exporters.Config{Name: "test", Interval: xtime.Duration(10 * time.Second), Align: true})
+
+	tests := []struct {
+		name string
+		at   time.Time
+		now  time.Time
+		want int
+	}{
+		{name: "same slot", at: time.Unix(10, 0), now: time.Unix(19, 0), want: 0},
+		{name: "next slot", at: time.Unix(10, 0), now: time.Unix(20, 0), want: 1},
+		{name: "several slots", at: time.Unix(10, 0), now: time.Unix(45, 0), want: 3},
+		{name: "across epoch", at: time.Unix(-10, 0), now: time.Unix(15, 0), want: 2},
+		{name: "before epoch", at: time.Unix(-25, 0), now: time.Unix(-5, 0), want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.missed(tt.at, tt.now); got != tt.want {
+				t.Errorf("missed(%s, %s) = %d, want %d", tt.at.UTC(), tt.now.UTC(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for _, interval := range []time.Duration{time.Nanosecond, time.Second, time.Minute, 24 * time.Hour} {
+		for _, name := range []string{"", "a", "b", "Living Room", "Weather Station"} {
+			got := jitter(name, interval)
+			if got < 0 || got >= interval {
+				t.Errorf("jitter(%q, %s) = %s, want in [0, %s)", name, interval, got, interval)
+			}
+			if again := jitter(name, interval); again != got {
+				t.Errorf("jitter(%q, %s) = %s, then %s, want the same", name, interval, got, again)
+			}
+		}
+	}
+
+	if got := jitter("test", 0); got != 0 {
+		t.Errorf("jitter(test, 0) = %s, want 0", got)
+	}
+	if jitter("a", time.Hour) == jitter("b", time.Hour) {
+		t.Errorf("jitter(a) = jitter(b), want different offsets")
+	}
+
+	// the collection times don't depend on when the schedule is created
+	cfg := exporters.Config{Name: "test", Interval: xtime.Duration(time.Minute)}
+	s := newSchedule(cfg)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	want := now.Truncate(time.Minute).Add(jitter(cfg.Name, time.Minute))
+	if got := s.next(now); !got.Equal(want) {
+		t.Errorf("next(%s) = %s, want %s", now, got.UTC(), want.UTC())
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/topi314/prometheus-collectors/exporters"
+)
+
+// runScrape implements the scrape subcommand which collects the named exporter once and prints its metrics, it returns the exit code.
+func runScrape(args []string) int {
+	fs := flag.NewFlagSet("scrape", flag.ExitOnError)
+	cfgPath := fs.String("config", "config.toml", "Path to config file")
+	name := fs.String("name", "", "Name of the exporter config to scrape")
+	format := fs.String("format", "text", "Output format, text (Prometheus text format) or json")
+	verbose := fs.Bool("verbose", false, "Print debug logs and the raw response bodies to stderr")
+	_ = fs.Parse(args)
+
+	level := slog.LevelWarn
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	if *format != "text" && *format != "json" {
+		slog.Error("Invalid format, must be text or json", slog.String("format", *format))
+		return 2
+	}
+
+	cfg, err := loadConfig(*cfgPath)
+	if err != nil {
+		slog.Error("Failed to load config", slog.Any("err", err))
+		return 1
+	}
+	if err = cfg.Validate(); err != nil {
+		slog.Error("Invalid config", slog.Any("err", err))
+		return 1
+	}
+
+	var (
+		config exporters.Config
+		found  bool
+	)
+	for _, c := range cfg.Configs {
+		if c.Name == *name {
+			config = cfg.Global.withDefaults(c)
+			found = true
+			break
+		}
+	}
+	if !found {
+		slog.Error("Exporter config not found", slog.String("name", *name))
+		return 1
+	}
+
+	registry := prometheus.NewRegistry()
+	collectErr := scrapeOnce(config, registry, *verbose)
+	if collectErr != nil {
+		slog.Error("Failed to collect", slog.String("reason", errorReason(collectErr)), slog.Any("err", collectErr))
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		slog.Error("Failed to gather metrics", slog.Any("err", err))
+		return 1
+	}
+
+	if *format == "json" {
+		err = printMetricsJSON(os.Stdout, families)
+	} else {
+		err = printMetricsText(os.Stdout, families)
+	}
+	if err != nil {
+		slog.Error("Failed to print metrics", slog.Any("err", err))
+		return 1
+	}
+
+	if collectErr != nil {
+		return 1
+	}
+	return 0
+}
+
+// scrapeOnce creates the exporter and collects once with the configured timeout.
+func scrapeOnce(cfg exporters.Config, registry prometheus.Registerer, verbose bool) error {
+	logger := slog.With(slog.String("name", cfg.Name), slog.String("type", cfg.Type))
+
+	// the factory is not closed, the metrics are printed after the exporter is closed
+	factory := exporters.NewRegistry(registry).Factory()
+	exporter, err := exporters.New(cfg, logger, factory)
+	if err != nil {
+		return fmt.Errorf("create exporter: %w", err)
+	}
+	defer func() {
+		if closeErr := exporter.Close(); closeErr != nil {
+			logger.Error("failed to close exporter", slog.Any("err", closeErr))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Timeout))
+	defer cancel()
+
+	if verbose {
+		ctx = exporters.WithResponseRecorder(ctx, func(rq *http.Request, rs *http.Response, body []byte) {
+			_, _ = fmt.Fprintf(os.Stderr, "%s %s -> %s\n%s\n\n", rq.Method, rq.URL.Redacted(), rs.Status, body)
+		})
+	}
+
+	return exporter.Collect(ctx)
+}
+
+func printMetricsText(w io.Writer, families []*dto.MetricFamily) error {
+	encoder := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	var errs []error
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type jsonMetric struct {
+	Name   string            `json:"name"`
+	Help   string            `json:"help"`
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels"`
+	// Value is the sample sum for histograms.
+	Value   jsonFloat         `json:"value"`
+	Count   uint64            `json:"count,omitempty"`
+	Buckets map[string]uint64 `json:"buckets,omitempty"`
+}
+
+func printMetricsJSON(w io.Writer, families []*dto.MetricFamily) error {
+	metrics := []jsonMetric{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string, len(metric.GetLabel()))
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			m := jsonMetric{
+				Name:   family.GetName(),
+				Help:   family.GetHelp(),
+				Type:   strings.ToLower(family.GetType().String()),
+				Labels: labels,
+				Value:  jsonFloat(metricValue(metric)),
+			}
+			if histogram := metric.GetHistogram(); histogram != nil {
+				m.Count = histogram.GetSampleCount()
+				m.Buckets = make(map[string]uint64, len(histogram.GetBucket()))
+				for _, bucket := range histogram.GetBucket() {
+					m.Buckets[strconv.FormatFloat(bucket.GetUpperBound(), 'g', -1, 64)] = bucket.GetCumulativeCount()
+				}
+			}
+			metrics = append(metrics, m)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(metrics)
+}
+
+// jsonFloat is a float64 which is encoded as "NaN", "+Inf" or "-Inf" string if it isn't finite, like in the Prometheus text format.
+// encoding/json fails on these values, e.g. of series marked stale with stale_mode nan.
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return []byte(`"` + strconv.FormatFloat(v, 'g', -1, 64) + `"`), nil
+	}
+	return json.Marshal(v)
+}
+
+func metricValue(metric *dto.Metric) float64 {
+	switch {
+	case metric.Gauge != nil:
+		return metric.GetGauge().GetValue()
+	case metric.Counter != nil:
+		return metric.GetCounter().GetValue()
+	case metric.Histogram != nil:
+		return metric.GetHistogram().GetSampleSum()
+	default:
+		return metric.GetUntyped().GetValue()
+	}
+}
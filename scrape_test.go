@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPrintMetricsJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+		want  any
+	}{
+		{name: "finite", value: 1.5, want: 1.5},
+		{name: "nan", value: math.NaN(), want: "NaN"},
+		{name: "inf", value: math.Inf(1), want: "+Inf"},
+		{name: "negative inf", value: math.Inf(-1), want: "-Inf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_value", Help: "Test"})
+			registry.MustRegister(gauge)
+			gauge.Set(tt.value)
+
+			families, err := registry.Gather()
+			if err != nil {
+				t.Fatalf("Gather() error = %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err = printMetricsJSON(&buf, families); err != nil {
+				t.Fatalf("printMetricsJSON() error = %v", err)
+			}
+
+			var metrics []map[string]any
+			if err = json.Unmarshal(buf.Bytes(), &metrics); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if len(metrics) != 1 || metrics[0]["value"] != tt.want {
+				t.Errorf("printMetricsJSON() = %s, want value %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"log/slog"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/topi314/prometheus-collectors/exporters"
+)
+
+var (
+	//go:embed status.html
+	statusPage     string
+	statusTemplate = template.Must(template.New("status").Parse(statusPage))
+)
+
+// exporterStatus is the state of an exporter shown by the status page and the exporters API.
+type exporterStatus struct {
+	Name                string             `json:"name"`
+	Type                string             `json:"type"`
+	Mode                exporters.Mode     `json:"mode"`
+	Interval            string             `json:"interval"`
+	Timeout             string             `json:"timeout"`
+	LastScrape          *time.Time         `json:"last_scrape"`
+	LastDuration        float64            `json:"last_duration_seconds"`
+	LastError           string             `json:"last_error,omitempty"`
+	ConsecutiveFailures int                `json:"consecutive_failures"`
+	CircuitBreaker      string             `json:"circuit_breaker,omitempty"`
+	Values              map[string]float64 `json:"values"`
+}
+
+func newStatusStore() *statusStore {
+	return &statusStore{
+		exporters: map[string]exporterStatus{},
+	}
+}
+
+// statusStore holds the status of the running exporters, it is updated by the exporters after each collection.
+type statusStore struct {
+	mu        sync.Mutex
+	exporters map[string]exporterStatus
+}
+
+// add adds the exporter without any collection.
+// The circuit breaker state is set by the running exporter, it stays empty if the exporter couldn't be created.
+func (s *statusStore) add(cfg exporters.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exporters[cfg.Name] = exporterStatus{
+		Name:     cfg.Name,
+		Type:     cfg.Type,
+		Mode:     cfg.Mode,
+		Interval: time.Duration(cfg.Interval).String(),
+		Timeout:  time.Duration(cfg.Timeout).String(),
+		Values:   map[string]float64{},
+	}
+}
+
+// update modifies the status of the exporter if it wasn't removed in the meantime.
+func (s *statusStore) update(name string, update func(status *exporterStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.exporters[name]
+	if !ok {
+		return
+	}
+	update(&status)
+	s.exporters[name] = status
+}
+
+func (s *statusStore) remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.exporters, name)
+}
+
+// list returns the status of all exporters sorted by name.
+func (s *statusStore) list() []exporterStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.SortedFunc(maps.Values(s.exporters), func(a exporterStatus, b exporterStatus) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+}
+
+// statusValues returns the values without NaN and Inf, which can't be encoded as JSON.
+func statusValues(values map[string]float64) map[string]float64 {
+	maps.DeleteFunc(values, func(_ string, value float64) bool {
+		return math.IsNaN(value) || math.IsInf(value, 0)
+	})
+	return values
+}
+
+// statusHandler serves an HTML page with the status of all exporters.
+func statusHandler(status *statusStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := statusTemplate.Execute(w, status.list()); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to render status page", slog.Any("err", err))
+		}
+	}
+}
+
+// exportersHandler serves the status of all exporters as JSON.
+func exportersHandler(status *statusStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(struct {
+			Exporters []exporterStatus `json:"exporters"`
+		}{
+			Exporters: status.list(),
+		}); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to write exporters response", slog.Any("err", err))
+		}
+	}
+}